@@ -13,6 +13,7 @@ import (
 	"github.com/wzshiming/profile_stats/generator"
 	"github.com/wzshiming/profile_stats/source"
 	"github.com/wzshiming/putingh"
+	"golang.org/x/oauth2"
 )
 
 const selfRepo = "https://github.com/wzshiming/profile_stats"
@@ -20,20 +21,154 @@ const selfRepo = "https://github.com/wzshiming/profile_stats"
 func main() {
 	ctx := context.Background()
 	token := os.Getenv("GH_TOKEN")
+	githubURL := os.Getenv("GITHUB_URL")
+	// The tokens of GH_TOKENS are rotated when the quota of one is exhausted
+	var tokens []string
+	for _, t := range strings.Split(os.Getenv("GH_TOKENS"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	if token == "" && len(tokens) != 0 {
+		token = tokens[0]
+	}
+	gitlabToken := os.Getenv("GL_TOKEN")
+	gitlabURL := os.Getenv("GITLAB_URL")
+	giteaToken := os.Getenv("GITEA_TOKEN")
+	giteaURL := os.Getenv("GITEA_URL")
 	warningExit, _ := strconv.ParseBool(os.Getenv("WARNING_EXIT"))
 	interval, _ := time.ParseDuration(os.Getenv("INTERVAL"))
+	timeout, _ := time.ParseDuration(os.Getenv("TIMEOUT"))
+	onError := os.Getenv("ON_ERROR")
+	switch onError {
+	default:
+		log.Printf("ON_ERROR: not support %q", onError)
+		os.Exit(2)
+	case "", generator.OnErrorKeep, generator.OnErrorComment, generator.OnErrorFail:
+	}
 	retry, _ := strconv.ParseInt(os.Getenv("RETRY"), 0, 64)
 	tmp := os.Getenv("TMP_DIR")
+	offline, _ := strconv.ParseBool(os.Getenv("OFFLINE"))
+	maxAge, err := source.ParseCacheMaxAge(os.Getenv("CACHE_MAX_AGE"))
+	if err != nil {
+		log.Println(err)
+		os.Exit(2)
+	}
+	record, _ := strconv.ParseBool(os.Getenv("RECORD"))
+	cache := source.CachePolicy{
+		Dir:      tmp,
+		MaxAge:   maxAge,
+		Offline:  offline,
+		Fixtures: os.Getenv("FIXTURES_DIR"),
+		Record:   record,
+	}
 	uris := os.Args[1:]
-	err := Update(ctx, token, tmp, interval, int(retry), warningExit, uris...)
+
+	if len(uris) != 0 && uris[0] == "prune" {
+		if tmp == "" {
+			log.Println("prune: no TMP_DIR")
+			os.Exit(2)
+		}
+		count, err := source.PruneCache(cache)
+		if err != nil {
+			log.Println(err)
+			os.Exit(2)
+		}
+		log.Printf("pruned %d entries in %s", count, tmp)
+		return
+	}
+	if offline && tmp == "" && cache.Fixtures == "" {
+		log.Println("offline: no TMP_DIR")
+		os.Exit(2)
+	}
+
+	// The GitHub App is authenticated instead of the token if its ID is set
+	var appTokens oauth2.TokenSource
+	if appID := os.Getenv("GH_APP_ID"); appID != "" {
+		appTokens, err = newGitHubAppTokenSource(githubURL, appID)
+		if err != nil {
+			log.Println(err)
+			os.Exit(2)
+		}
+		t, err := appTokens.Token()
+		if err != nil {
+			log.Println(err)
+			os.Exit(2)
+		}
+		// The installation token lasts an hour, it's enough to write the results
+		token = t.AccessToken
+	}
+
+	sources := source.NewRegistry("github")
+	sources.Register("github", func(baseURL string) (source.Source, error) {
+		if baseURL == "" {
+			baseURL = githubURL
+		}
+		if appTokens != nil {
+			return source.NewGitHubWithTokenSource(baseURL, appTokens, cache, interval, int(retry))
+		}
+		if len(tokens) > 1 {
+			return source.NewGitHubWithTokens(baseURL, tokens, cache, interval, int(retry))
+		}
+		return source.NewGitHub(baseURL, token, cache, interval, int(retry))
+	})
+	sources.Register("gitlab", func(baseURL string) (source.Source, error) {
+		if baseURL == "" {
+			baseURL = gitlabURL
+		}
+		return source.NewGitLab(baseURL, gitlabToken, cache, interval, int(retry)), nil
+	})
+	newGitea := func(baseURL string) (source.Source, error) {
+		if baseURL == "" {
+			baseURL = giteaURL
+		}
+		if baseURL == "" {
+			return nil, fmt.Errorf("no base url")
+		}
+		return source.NewGitea(baseURL, giteaToken, cache, interval, int(retry)), nil
+	}
+	sources.Register("gitea", newGitea)
+	sources.Register("forgejo", newGitea)
+	sources.Register("git", func(baseURL string) (source.Source, error) {
+		if baseURL == "" {
+			return nil, fmt.Errorf("no repository path")
+		}
+		return source.NewGit(baseURL), nil
+	})
+	err = Update(ctx, token, githubURL, tmp, sources, warningExit, timeout, onError, uris...)
 	if err != nil {
 		log.Println(err)
 		os.Exit(2)
 	}
 }
 
-func Update(ctx context.Context, token, tmp string, interval time.Duration, retry int, warningExit bool, uris ...string) error {
-	putCli := putingh.NewPutInGH(token,
+// newGitHubAppTokenSource returns the tokens of the installation of GitHub App by the environment variables,
+// the private key is GH_APP_PRIVATE_KEY or the file GH_APP_PRIVATE_KEY_FILE.
+func newGitHubAppTokenSource(githubURL, appID string) (oauth2.TokenSource, error) {
+	id, err := strconv.ParseInt(appID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("GH_APP_ID: %w", err)
+	}
+	installationID, err := strconv.ParseInt(os.Getenv("GH_APP_INSTALLATION_ID"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("GH_APP_INSTALLATION_ID: %w", err)
+	}
+	key := []byte(os.Getenv("GH_APP_PRIVATE_KEY"))
+	if len(key) == 0 {
+		file := os.Getenv("GH_APP_PRIVATE_KEY_FILE")
+		if file == "" {
+			return nil, fmt.Errorf("no GH_APP_PRIVATE_KEY or GH_APP_PRIVATE_KEY_FILE")
+		}
+		key, err = os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("GH_APP_PRIVATE_KEY_FILE: %w", err)
+		}
+	}
+	return source.NewGitHubAppTokenSource(githubURL, id, installationID, key)
+}
+
+func Update(ctx context.Context, token, githubURL, tmp string, sources *source.Registry, warningExit bool, timeout time.Duration, onError string, uris ...string) error {
+	opts := []putingh.Option{
 		putingh.WithGitCommitMessage(func(owner, repo, branch, name, path string) string {
 			return fmt.Sprintf(`Automatic update %s
 
@@ -41,11 +176,17 @@ For details see %s
 `, name, selfRepo)
 		}),
 		putingh.WithTmpDir(tmp),
-	)
+	}
+	// Only the git scheme is read and written on the host of GitHub Enterprise Server,
+	// the others use the API of github.com in putingh.
+	enterprise := githubURL != "" && strings.TrimSuffix(githubURL, "/") != source.GitHubURL
+	if enterprise {
+		opts = append(opts, putingh.WithHost(strings.TrimSuffix(githubURL, "/")))
+	}
+	putCli := putingh.NewPutInGH(token, opts...)
 
 	buf := bytes.NewBuffer(nil)
-	src := source.NewSource(token, tmp, interval, retry)
-	regi := generator.NewHandler(src)
+	regi := generator.NewHandler(sources, timeout, onError)
 	for _, uri := range uris {
 		buf.Reset()
 		local := !strings.Contains(uri, ":/")
@@ -60,6 +201,9 @@ For details see %s
 				return fmt.Errorf("read %s: %w", uri, err)
 			}
 		} else {
+			if enterprise && !strings.HasPrefix(uri, "git:") {
+				return fmt.Errorf("open %s: only git scheme is supported on %s", uri, githubURL)
+			}
 			r, err := putCli.GetFrom(ctx, uri)
 			if err != nil {
 				return fmt.Errorf("open %s: %w", uri, err)
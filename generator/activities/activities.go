@@ -6,6 +6,7 @@ import (
 	"io"
 	"log"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,21 +14,45 @@ import (
 	"github.com/wzshiming/profile_stats/generator/activities/render"
 	"github.com/wzshiming/profile_stats/source"
 	"github.com/wzshiming/profile_stats/utils"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	TypePRs    = "prs"
+	TypeIssues = "issues"
 )
 
 type Activities struct {
-	source *source.Source
+	sources *source.Registry
 }
 
-func NewActivities(src *source.Source) *Activities {
+func NewActivities(sources *source.Registry) *Activities {
 	return &Activities{
-		source: src,
+		sources: sources,
 	}
 }
 
 func (a *Activities) Generate(ctx context.Context, w io.Writer, args profile_stats.Args) (err error) {
+	// The pull requests of the scopes are listed, only of the users if any
+	var scopes []source.Scope
+	scopesSlice, _ := args.StringSlice("scope")
+	for _, s := range scopesSlice {
+		scope, err := source.ParseScope(s)
+		if err != nil {
+			return err
+		}
+		scopes = append(scopes, scope)
+	}
+	// The pull requests found by the search query are listed like a scope
+	if query, _ := args.String("query"); query != "" {
+		scopes = append(scopes, source.Scope{
+			Kind: source.ScopeSearch,
+			Name: query,
+		})
+	}
+
 	usernames, ok := args.StringSlice("username")
-	if !ok {
+	if !ok && len(scopes) == 0 {
 		return fmt.Errorf("no usernames")
 	}
 
@@ -54,7 +79,27 @@ func (a *Activities) Generate(ctx context.Context, w io.Writer, args profile_sta
 	labels, _ := args.StringSlice("labels")
 	labelsFilter, _ := args.StringSlice("labels_filter")
 
+	listPRs, listIssues := false, false
+	types, ok := args.StringSlice("type")
+	if !ok {
+		types = []string{TypePRs}
+	}
+	for _, typ := range types {
+		switch strings.ToLower(typ) {
+		default:
+			return fmt.Errorf("can't support type %q", typ)
+		case TypePRs:
+			listPRs = true
+		case TypeIssues:
+			listIssues = true
+		}
+	}
+	if listIssues && len(scopes) != 0 {
+		return fmt.Errorf("can't support type %q with scope or query", TypeIssues)
+	}
+
 	var states []source.PullRequestState
+	var issueStates []source.IssueState
 	statesSlice, ok := args.StringSlice("states")
 	if ok {
 		states = make([]source.PullRequestState, 0, len(statesSlice))
@@ -63,20 +108,184 @@ func (a *Activities) Generate(ctx context.Context, w io.Writer, args profile_sta
 			switch s {
 			default:
 				return fmt.Errorf("can't support %q", state)
-			case source.PullRequestStateOpen, source.PullRequestStateClosed, source.PullRequestStateMerged:
+			case source.PullRequestStateOpen, source.PullRequestStateClosed:
+				issueStates = append(issueStates, source.IssueState(s))
+			case source.PullRequestStateMerged:
 			}
 			states = append(states, s)
 		}
+		// Issues can't be merged
+		if listIssues && len(states) != 0 && len(issueStates) == 0 {
+			return fmt.Errorf("can't support type %q with states %q", TypeIssues, strings.Join(statesSlice, ","))
+		}
 	}
 	if len(states) == 0 {
 		states = []source.PullRequestState{source.PullRequestStateOpen, source.PullRequestStateClosed, source.PullRequestStateMerged}
 	}
+	if len(issueStates) == 0 {
+		issueStates = []source.IssueState{source.IssueStateOpen, source.IssueStateClosed}
+	}
+
+	var filter pullRequestFilter
+	if v, ok := args.String("draft"); ok && v != "" {
+		draft, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("draft: %w", err)
+		}
+		filter.draft = &draft
+	}
+	reviewDecisions, _ := args.StringSlice("review_decision")
+	for _, decision := range reviewDecisions {
+		d := source.PullRequestReviewDecision(strings.ToUpper(decision))
+		switch d {
+		default:
+			return fmt.Errorf("can't support review decision %q", decision)
+		case source.PullRequestReviewDecisionApproved, source.PullRequestReviewDecisionChangesRequested, source.PullRequestReviewDecisionReviewRequired:
+		}
+		filter.reviewDecisions = append(filter.reviewDecisions, d)
+	}
+	filter.reviewers, _ = args.StringSlice("reviewers")
+	filter.approvers, _ = args.StringSlice("approvers")
+	filter.mergedBy, _ = args.StringSlice("merged_by")
+	mergeStrategies, _ := args.StringSlice("merge_strategy")
+	for _, strategy := range mergeStrategies {
+		m := source.PullRequestMergeMethod(strings.ToUpper(strategy))
+		switch m {
+		default:
+			return fmt.Errorf("can't support merge strategy %q", strategy)
+		case source.PullRequestMergeMethodMerge, source.PullRequestMergeMethodSquash, source.PullRequestMergeMethodRebase:
+		}
+		filter.mergeStrategies = append(filter.mergeStrategies, m)
+	}
+
+	columns, _ := args.StringSlice("columns")
+	for _, column := range columns {
+		if _, ok := render.Columns[column]; !ok {
+			return fmt.Errorf("can't support column %q", column)
+		}
+	}
+
+	concurrency, ok := args.Int("concurrency")
+	if !ok || concurrency <= 0 {
+		concurrency = utils.DefaultConcurrency
+	}
+
+	provider, _ := args.String("provider")
+	baseURL, _ := args.String("base_url")
+	src, err := a.sources.Get(provider, baseURL)
+	if err != nil {
+		return err
+	}
+
+	if len(scopes) != 0 {
+		return a.GetScopes(ctx, w, src, scopes, usernames, size, states, repository, branch, labels, labelsFilter, filter, columns, last, concurrency)
+	}
+	return a.Get(ctx, w, src, usernames, size, listPRs, listIssues, states, issueStates, repository, branch, labels, labelsFilter, filter, columns, last, concurrency)
+}
+
+// pullRequestFilter selects the pull requests by the reviews, the draft and the merger
+type pullRequestFilter struct {
+	draft           *bool
+	reviewDecisions []source.PullRequestReviewDecision
+	reviewers       []string
+	approvers       []string
+	mergedBy        []string
+	mergeStrategies []source.PullRequestMergeMethod
+}
+
+func (f pullRequestFilter) match(pr *source.PullRequest) bool {
+	if f.draft != nil && pr.IsDraft != *f.draft {
+		return false
+	}
+	if len(f.reviewDecisions) != 0 {
+		match := false
+		for _, d := range f.reviewDecisions {
+			if string(d) == pr.ReviewDecision {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	if len(f.reviewers) != 0 && !matchAny(f.reviewers, pr.RequestedReviewers) {
+		return false
+	}
+	if len(f.approvers) != 0 && !matchAny(f.approvers, pr.Approvers) {
+		return false
+	}
+	if len(f.mergedBy) != 0 && (pr.MergedBy == "" || !utils.Match(f.mergedBy, pr.MergedBy)) {
+		return false
+	}
+	if len(f.mergeStrategies) != 0 {
+		match := false
+		for _, m := range f.mergeStrategies {
+			if string(m) == pr.MergeStrategy {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	return true
+}
+
+// matchAny reports whether any of the names matches the patterns
+func matchAny(patterns []string, names []string) bool {
+	for _, name := range names {
+		if utils.Match(patterns, name) {
+			return true
+		}
+	}
+	return false
+}
+
+type activity struct {
+	sortTime time.Time
+	item     render.ActivitiesItem
+}
+
+func (a *Activities) Get(ctx context.Context, w io.Writer, src source.Source, usernames []string, size int, listPRs, listIssues bool, states []source.PullRequestState, issueStates []source.IssueState, repository, branch, labels, labelsFilter []string, filter pullRequestFilter, columns []string, last time.Time, concurrency int) error {
+	items := []activity{}
+
+	cbs := []source.PullRequestCallback{}
+	issueCbs := []source.IssueCallback{}
+	if !last.IsZero() {
+		cbs = append(cbs, func(pr *source.PullRequest) bool {
+			return pr.CreatedAt.After(last)
+		})
+		issueCbs = append(issueCbs, func(issue *source.Issue) bool {
+			return issue.CreatedAt.After(last)
+		})
+	}
 
-	return a.Get(ctx, w, usernames, size, states, repository, branch, labels, labelsFilter, last)
+	usernames, attrs := utils.KeyAttribute(usernames)
+
+	results := make([][]activity, len(usernames))
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for i, username := range usernames {
+		g.Go(func() (err error) {
+			results[i], err = a.list(ctx, src, username, attrs[username], size, listPRs, listIssues, states, issueStates, repository, branch, labels, labelsFilter, filter, last, cbs, issueCbs)
+			return err
+		})
+	}
+	err := g.Wait()
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		items = append(items, result...)
+	}
+	return renderActivities(w, items, columns)
 }
 
-func (a *Activities) Get(ctx context.Context, w io.Writer, usernames []string, size int, states []source.PullRequestState, repository, branch, labels, labelsFilter []string, last time.Time) error {
-	items := []*source.PullRequest{}
+// GetScopes renders the pull requests of the scopes, only of the usernames if any
+func (a *Activities) GetScopes(ctx context.Context, w io.Writer, src source.Source, scopes []source.Scope, usernames []string, size int, states []source.PullRequestState, repository, branch, labels, labelsFilter []string, filter pullRequestFilter, columns []string, last time.Time, concurrency int) error {
+	items := []activity{}
 
 	cbs := []source.PullRequestCallback{}
 	if !last.IsZero() {
@@ -87,112 +296,242 @@ func (a *Activities) Get(ctx context.Context, w io.Writer, usernames []string, s
 
 	usernames, attrs := utils.KeyAttribute(usernames)
 
-	for _, username := range usernames {
-		prs, err := a.source.PullRequests(ctx, username,
+	results := make([][]activity, len(scopes))
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for i, scope := range scopes {
+		g.Go(func() (err error) {
+			results[i], err = a.listScope(ctx, src, scope, usernames, attrs, size, states, repository, branch, labels, labelsFilter, filter, last, cbs)
+			return err
+		})
+	}
+	err := g.Wait()
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		items = append(items, result...)
+	}
+	return renderActivities(w, items, columns)
+}
+
+func renderActivities(w io.Writer, items []activity, columns []string) error {
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].sortTime.After(items[j].sortTime)
+	})
+	data := render.ActivitiesData{
+		Items:   make([]render.ActivitiesItem, 0, len(items)),
+		Columns: columns,
+	}
+	for _, item := range items {
+		data.Items = append(data.Items, item.item)
+	}
+
+	return render.ActivitiesRender(w, data)
+}
+
+// listScope lists the activities of the pull requests in the scope, the attributes of the authors are applied
+func (a *Activities) listScope(ctx context.Context, src source.Source, scope source.Scope, usernames []string, attrs map[string]map[string]string, size int, states []source.PullRequestState, repository, branch, labels, labelsFilter []string, filter pullRequestFilter, last time.Time, cbs []source.PullRequestCallback) ([]activity, error) {
+	prs, err := src.ScopedPullRequests(ctx, scope,
+		states,
+		source.IssueOrderFieldCreatedAt, source.OrderDirectionDesc, size,
+		cbs...)
+	if err != nil {
+		return nil, fmt.Errorf("list PullRequests of %s: %w", scope, err)
+	}
+
+	items := []activity{}
+	matches := map[string]func(repo string, itemLabels []string, sortTime time.Time) bool{}
+	for _, pr := range prs {
+		attr, ok := attrs[pr.Username]
+		if !ok && len(usernames) != 0 {
+			continue
+		}
+		match, ok := matches[pr.Username]
+		if !ok {
+			match = matcher(attr, last, labels, repository)
+			matches[pr.Username] = match
+		}
+		if item, ok := pullRequestActivity(pr, attr, branch, labelsFilter, filter, match); ok {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// list lists the activities of the user
+func (a *Activities) list(ctx context.Context, src source.Source, username string, attr map[string]string, size int, listPRs, listIssues bool, states []source.PullRequestState, issueStates []source.IssueState, repository, branch, labels, labelsFilter []string, filter pullRequestFilter, last time.Time, cbs []source.PullRequestCallback, issueCbs []source.IssueCallback) ([]activity, error) {
+	items := []activity{}
+	match := matcher(attr, last, labels, repository)
+
+	if listPRs {
+		prs, err := src.PullRequests(ctx, username,
 			states,
 			source.IssueOrderFieldCreatedAt, source.OrderDirectionDesc, size,
 			cbs...)
 		if err != nil {
-			return fmt.Errorf("list PullRequests %q: %w", username, err)
+			return nil, fmt.Errorf("list PullRequests %q: %w", username, err)
 		}
 
-		var before, after time.Time
-		if v := attrs[username]["before"]; v != "" {
-			before, err = utils.ParseTime(v, time.Local)
-			if err != nil {
-				log.Printf("error ParseTime: %s", err)
+		for _, pr := range prs {
+			if item, ok := pullRequestActivity(pr, attr, branch, labelsFilter, filter, match); ok {
+				items = append(items, item)
 			}
 		}
-		if v := attrs[username]["after"]; v != "" {
-			after, err = utils.ParseTime(v, time.Local)
-			if err != nil {
-				log.Printf("error ParseTime: %s", err)
-			}
+	}
+
+	if listIssues {
+		issues, err := src.Issues(ctx, username,
+			issueStates,
+			source.IssueOrderFieldCreatedAt, source.OrderDirectionDesc, size,
+			issueCbs...)
+		if err != nil {
+			return nil, fmt.Errorf("list Issues %q: %w", username, err)
 		}
 
-		for _, pr := range prs {
-			if pr.SortTime.Before(last) {
-				continue
-			}
-			if len(branch) != 0 && !utils.Match(branch, pr.BaseRef) {
-				continue
-			}
-			if len(labels) != 0 {
-				match := false
-				for _, label := range pr.Labels {
-					if utils.Match(labels, label) {
-						match = true
-						break
-					}
-				}
-				if !match {
-					continue
-				}
-			}
-			if len(repository) != 0 {
-				repo := strings.TrimPrefix(strings.Split(pr.URL.Path, "/pull/")[0], "/")
-				if !utils.Match(repository, repo) {
-					continue
-				}
-			}
-			if !before.IsZero() && !pr.SortTime.Before(before) {
-				continue
-			}
-			if !after.IsZero() && !pr.SortTime.After(after) {
+		for _, issue := range issues {
+			if !match(issue.Repository, issue.Labels, issue.SortTime) {
 				continue
 			}
 
-			if n := attrs[username]["name"]; n != "" {
-				pr.Username = n
+			if n := attr["name"]; n != "" {
+				issue.Username = n
 			}
 			if len(labelsFilter) != 0 {
-				list := make([]string, 0, len(pr.Labels))
-				for _, label := range pr.Labels {
-					if utils.Match(labelsFilter, label) {
-						list = append(list, label)
-					}
+				issue.Labels = filterLabels(labelsFilter, issue.Labels)
+			}
+			items = append(items, activity{
+				sortTime: issue.SortTime,
+				item:     formatIssue(issue),
+			})
+		}
+	}
+	return items, nil
+}
+
+// matcher returns the filter of the items, the before and after of the attribute of the user are applied
+func matcher(attr map[string]string, last time.Time, labels, repository []string) func(repo string, itemLabels []string, sortTime time.Time) bool {
+	var err error
+	var before, after time.Time
+	if v := attr["before"]; v != "" {
+		before, err = utils.ParseTime(v, time.Local)
+		if err != nil {
+			log.Printf("error ParseTime: %s", err)
+		}
+	}
+	if v := attr["after"]; v != "" {
+		after, err = utils.ParseTime(v, time.Local)
+		if err != nil {
+			log.Printf("error ParseTime: %s", err)
+		}
+	}
+
+	return func(repo string, itemLabels []string, sortTime time.Time) bool {
+		if sortTime.Before(last) {
+			return false
+		}
+		if len(labels) != 0 {
+			match := false
+			for _, label := range itemLabels {
+				if utils.Match(labels, label) {
+					match = true
+					break
 				}
-				pr.Labels = list
 			}
-			items = append(items, pr)
+			if !match {
+				return false
+			}
+		}
+		if len(repository) != 0 && !utils.Match(repository, repo) {
+			return false
+		}
+		if !before.IsZero() && !sortTime.Before(before) {
+			return false
 		}
+		if !after.IsZero() && !sortTime.After(after) {
+			return false
+		}
+		return true
 	}
+}
 
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].SortTime.After(items[j].SortTime)
-	})
-	data := render.ActivitiesData{
-		Items: formatSourceActivities(items),
+// pullRequestActivity converts the pull request if it matches the filters
+func pullRequestActivity(pr *source.PullRequest, attr map[string]string, branch, labelsFilter []string, filter pullRequestFilter, match func(repo string, itemLabels []string, sortTime time.Time) bool) (activity, bool) {
+	if len(branch) != 0 && !utils.Match(branch, pr.BaseRef) {
+		return activity{}, false
+	}
+	if !filter.match(pr) {
+		return activity{}, false
+	}
+	if !match(pr.Repository, pr.Labels, pr.SortTime) {
+		return activity{}, false
 	}
 
-	return render.ActivitiesRender(w, data)
+	if n := attr["name"]; n != "" {
+		pr.Username = n
+	}
+	if len(labelsFilter) != 0 {
+		pr.Labels = filterLabels(labelsFilter, pr.Labels)
+	}
+	return activity{
+		sortTime: pr.SortTime,
+		item:     formatPullRequest(pr),
+	}, true
 }
 
-func formatSourceActivities(prs []*source.PullRequest) []render.ActivitiesItem {
-	items := make([]render.ActivitiesItem, 0, len(prs))
-	for _, pr := range prs {
-		refAndIndex := strings.SplitN(pr.URL.Path, "/pull/", 2)
-		ref := strings.TrimPrefix(refAndIndex[0], "/")
-		index := refAndIndex[1]
-		item := render.ActivitiesItem{
-			URL:          pr.URL.String(),
-			Username:     pr.Username,
-			Link:         fmt.Sprintf("%s#%s", ref, index),
-			Title:        pr.Title,
-			BaseRef:      pr.BaseRef,
-			State:        pr.State,
-			Additions:    pr.Additions,
-			Deletions:    pr.Deletions,
-			Commits:      pr.Commits,
-			ChangedFiles: pr.ChangedFiles,
-			ChangeSize:   pr.ChangeSize,
-			CreatedAt:    pr.CreatedAt,
-			ClosedAt:     pr.ClosedAt,
-			MergedAt:     pr.MergedAt,
-			UpdatedAt:    pr.UpdatedAt,
-			Labels:       pr.Labels,
-		}
-		items = append(items, item)
-	}
-	return items
+func filterLabels(filter []string, labels []string) []string {
+	list := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if utils.Match(filter, label) {
+			list = append(list, label)
+		}
+	}
+	return list
+}
+
+func formatPullRequest(pr *source.PullRequest) render.ActivitiesItem {
+	return render.ActivitiesItem{
+		Type:         render.TypePullRequest,
+		URL:          pr.URL.String(),
+		Username:     pr.Username,
+		Link:         fmt.Sprintf("%s#%d", pr.Repository, pr.Number),
+		Title:        pr.Title,
+		BaseRef:      pr.BaseRef,
+		State:        pr.State,
+		Additions:    pr.Additions,
+		Deletions:    pr.Deletions,
+		Commits:      pr.Commits,
+		ChangedFiles: pr.ChangedFiles,
+		ChangeSize:   pr.ChangeSize,
+		CreatedAt:    pr.CreatedAt,
+		ClosedAt:     pr.ClosedAt,
+		MergedAt:     pr.MergedAt,
+		UpdatedAt:    pr.UpdatedAt,
+		Labels:       pr.Labels,
+
+		IsDraft:            pr.IsDraft,
+		ReviewDecision:     pr.ReviewDecision,
+		RequestedReviewers: pr.RequestedReviewers,
+		Approvers:          pr.Approvers,
+		Comments:           pr.Comments,
+		MergedBy:           pr.MergedBy,
+		TimeToFirstReview:  pr.TimeToFirstReview(),
+		MergeStrategy:      pr.MergeStrategy,
+	}
+}
+
+func formatIssue(issue *source.Issue) render.ActivitiesItem {
+	return render.ActivitiesItem{
+		Type:      render.TypeIssue,
+		URL:       issue.URL.String(),
+		Username:  issue.Username,
+		Link:      fmt.Sprintf("%s#%d", issue.Repository, issue.Number),
+		Title:     issue.Title,
+		State:     issue.State,
+		Comments:  issue.Comments,
+		CreatedAt: issue.CreatedAt,
+		ClosedAt:  issue.ClosedAt,
+		UpdatedAt: issue.UpdatedAt,
+		Labels:    issue.Labels,
+	}
 }
@@ -3,6 +3,7 @@ package render
 import (
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"time"
 
@@ -10,11 +11,43 @@ import (
 	"github.com/wzshiming/profile_stats/source"
 )
 
+const (
+	TypePullRequest = "pr"
+	TypeIssue       = "issue"
+)
+
+// The optional columns of the pull requests
+const (
+	ColumnDraft          = "draft"
+	ColumnReviewDecision = "review_decision"
+	ColumnReviewers      = "reviewers"
+	ColumnApprovers      = "approvers"
+	ColumnComments       = "comments"
+	ColumnMergedBy       = "merged_by"
+	ColumnFirstReview    = "first_review"
+	ColumnMergeStrategy  = "merge_strategy"
+)
+
+// Columns are the headers of the optional columns
+var Columns = map[string]string{
+	ColumnDraft:          "Draft",
+	ColumnReviewDecision: "Review Decision",
+	ColumnReviewers:      "Reviewers",
+	ColumnApprovers:      "Approvers",
+	ColumnComments:       "Comments",
+	ColumnMergedBy:       "Merged By",
+	ColumnFirstReview:    "First Review",
+	ColumnMergeStrategy:  "Merge Strategy",
+}
+
 type ActivitiesData struct {
 	Items []ActivitiesItem
+	// Columns are the optional columns shown before the labels, they are not shown in the table of issues only
+	Columns []string
 }
 
 type ActivitiesItem struct {
+	Type         string
 	URL          string
 	Username     string
 	Link         string
@@ -26,14 +59,34 @@ type ActivitiesItem struct {
 	Commits      int
 	ChangedFiles int
 	ChangeSize   string
+	Comments     int
 	CreatedAt    time.Time
 	ClosedAt     time.Time
 	MergedAt     time.Time
 	UpdatedAt    time.Time
 	Labels       []string
+
+	IsDraft            bool
+	ReviewDecision     string
+	RequestedReviewers []string
+	Approvers          []string
+	MergedBy           string
+	TimeToFirstReview  time.Duration
+	MergeStrategy      string
 }
 
 func ActivitiesRender(w io.Writer, data ActivitiesData) error {
+	hasPRs, hasIssues := false, false
+	for _, item := range data.Items {
+		if item.Type == TypeIssue {
+			hasIssues = true
+		} else {
+			hasPRs = true
+		}
+	}
+	issuesOnly := hasIssues && !hasPRs
+	mixed := hasIssues && hasPRs
+
 	t := make([][]string, 0, len(data.Items))
 	for _, item := range data.Items {
 		label := strings.Join(item.Labels, "<br/>")
@@ -56,15 +109,49 @@ func ActivitiesRender(w io.Writer, data ActivitiesData) error {
 			state = fmt.Sprintf("Closed<br/>%s", closedAt)
 		}
 
-		change := fmt.Sprintf("%s<br/>(+%d,-%d)/%d/%d", item.ChangeSize, item.Additions, item.Deletions, item.Commits, item.ChangedFiles)
 		link := fmt.Sprintf("[%s](%s)", item.Link, item.URL)
-		t = append(t, []string{
-			link, item.BaseRef, state, item.Username, change, label,
-		})
+		switch {
+		case issuesOnly:
+			t = append(t, []string{
+				link, state, item.Username, strconv.Itoa(item.Comments), label,
+			})
+		case !mixed:
+			change := fmt.Sprintf("%s<br/>(+%d,-%d)/%d/%d", item.ChangeSize, item.Additions, item.Deletions, item.Commits, item.ChangedFiles)
+			row := []string{
+				link, item.BaseRef, state, item.Username, change,
+			}
+			row = append(row, columns(item, data.Columns)...)
+			t = append(t, append(row, label))
+		case item.Type == TypeIssue:
+			change := fmt.Sprintf("%d comments", item.Comments)
+			row := []string{
+				link, "Issue", "", state, item.Username, change,
+			}
+			row = append(row, columns(item, data.Columns)...)
+			t = append(t, append(row, label))
+		default:
+			change := fmt.Sprintf("%s<br/>(+%d,-%d)/%d/%d", item.ChangeSize, item.Additions, item.Deletions, item.Commits, item.ChangedFiles)
+			row := []string{
+				link, "PR", item.BaseRef, state, item.Username, change,
+			}
+			row = append(row, columns(item, data.Columns)...)
+			t = append(t, append(row, label))
+		}
+	}
+	headers := make([]string, 0, len(data.Columns))
+	for _, column := range data.Columns {
+		headers = append(headers, Columns[column])
 	}
 	table := tablewriter.NewWriter(w)
 	table.SetAutoFormatHeaders(false)
-	table.SetHeader([]string{"Link", "Branch", "State", "Username", "Change Size/Commits/File", "Labels"})
+	switch {
+	case issuesOnly:
+		table.SetHeader([]string{"Link", "State", "Username", "Comments", "Labels"})
+	case !mixed:
+		table.SetHeader(append(append([]string{"Link", "Branch", "State", "Username", "Change Size/Commits/File"}, headers...), "Labels"))
+	default:
+		table.SetHeader(append(append([]string{"Link", "Type", "Branch", "State", "Username", "Change Size/Commits/File or Comments"}, headers...), "Labels"))
+	}
 	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
 	table.SetCenterSeparator("|")
 	table.AppendBulk(t)
@@ -72,6 +159,63 @@ func ActivitiesRender(w io.Writer, data ActivitiesData) error {
 	return nil
 }
 
+// columns returns the optional columns of the item, the columns of the pull requests are empty for the issues
+func columns(item ActivitiesItem, names []string) []string {
+	row := make([]string, 0, len(names))
+	for _, name := range names {
+		if item.Type == TypeIssue && name != ColumnComments {
+			row = append(row, "")
+			continue
+		}
+		var cell string
+		switch name {
+		case ColumnDraft:
+			if item.IsDraft {
+				cell = "Draft"
+			}
+		case ColumnReviewDecision:
+			cell = strings.ReplaceAll(formatEnum(item.ReviewDecision), " ", "&nbsp;")
+		case ColumnReviewers:
+			cell = strings.Join(item.RequestedReviewers, "<br/>")
+		case ColumnApprovers:
+			cell = strings.Join(item.Approvers, "<br/>")
+		case ColumnComments:
+			cell = strconv.Itoa(item.Comments)
+		case ColumnMergedBy:
+			cell = item.MergedBy
+		case ColumnFirstReview:
+			if item.TimeToFirstReview != 0 {
+				cell = formatDuration(item.TimeToFirstReview)
+			}
+		case ColumnMergeStrategy:
+			cell = formatEnum(item.MergeStrategy)
+		}
+		row = append(row, cell)
+	}
+	return row
+}
+
+// formatEnum formats the enum like CHANGES_REQUESTED to Changes requested
+func formatEnum(s string) string {
+	if s == "" {
+		return ""
+	}
+	s = strings.ToLower(strings.ReplaceAll(s, "_", " "))
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// formatDuration formats the duration in minutes, hours or days
+func formatDuration(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	}
+}
+
 func formatTime(t time.Time) string {
 	return t.Local().Format("2006-01-02")
 }
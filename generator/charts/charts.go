@@ -13,15 +13,16 @@ import (
 	placeholder_render "github.com/wzshiming/profile_stats/generator/placeholder/render"
 	"github.com/wzshiming/profile_stats/source"
 	"github.com/wzshiming/profile_stats/utils"
+	"golang.org/x/sync/errgroup"
 )
 
 type Charts struct {
-	source *source.Source
+	sources *source.Registry
 }
 
-func NewCharts(src *source.Source) *Charts {
+func NewCharts(sources *source.Registry) *Charts {
 	return &Charts{
-		source: src,
+		sources: sources,
 	}
 }
 
@@ -31,8 +32,26 @@ const (
 )
 
 func (a *Charts) Generate(ctx context.Context, w io.Writer, args profile_stats.Args) (err error) {
+	// Each scope is a series of the pull requests in it, only of the users if any
+	var scopes []source.Scope
+	scopesSlice, _ := args.StringSlice("scope")
+	for _, s := range scopesSlice {
+		scope, err := source.ParseScope(s)
+		if err != nil {
+			return err
+		}
+		scopes = append(scopes, scope)
+	}
+	// The pull requests found by the search query are listed like a scope
+	if query, _ := args.String("query"); query != "" {
+		scopes = append(scopes, source.Scope{
+			Kind: source.ScopeSearch,
+			Name: query,
+		})
+	}
+
 	usernames, ok := args.StringSlice("username")
-	if !ok {
+	if !ok && len(scopes) == 0 {
 		return fmt.Errorf("no usernames")
 	}
 
@@ -63,6 +82,18 @@ func (a *Charts) Generate(ctx context.Context, w io.Writer, args profile_stats.A
 	repository, _ := args.StringSlice("repository")
 	branch, _ := args.StringSlice("branch")
 
+	var mergeStrategies []source.PullRequestMergeMethod
+	mergeStrategiesSlice, _ := args.StringSlice("merge_strategy")
+	for _, strategy := range mergeStrategiesSlice {
+		m := source.PullRequestMergeMethod(strings.ToUpper(strategy))
+		switch m {
+		default:
+			return fmt.Errorf("can't support merge strategy %q", strategy)
+		case source.PullRequestMergeMethodMerge, source.PullRequestMergeMethodSquash, source.PullRequestMergeMethodRebase:
+		}
+		mergeStrategies = append(mergeStrategies, m)
+	}
+
 	var states []source.PullRequestState
 	statesSlice, ok := args.StringSlice("states")
 	if ok {
@@ -101,10 +132,25 @@ func (a *Charts) Generate(ctx context.Context, w io.Writer, args profile_stats.A
 		maxVal = 49
 	}
 
-	return a.Get(ctx, w, title, usernames, size, states, repository, branch, last, kind, width, height, maxVal)
+	concurrency, ok := args.Int("concurrency")
+	if !ok || concurrency <= 0 {
+		concurrency = utils.DefaultConcurrency
+	}
+
+	provider, _ := args.String("provider")
+	baseURL, _ := args.String("base_url")
+	src, err := a.sources.Get(provider, baseURL)
+	if err != nil {
+		return err
+	}
+
+	if len(scopes) != 0 {
+		return a.GetScopes(ctx, w, src, title, scopes, usernames, size, states, repository, branch, mergeStrategies, last, kind, width, height, maxVal, concurrency)
+	}
+	return a.Get(ctx, w, src, title, usernames, size, states, repository, branch, mergeStrategies, last, kind, width, height, maxVal, concurrency)
 }
 
-func (a *Charts) Get(ctx context.Context, w io.Writer, title string, usernames []string, size int, states []source.PullRequestState, repository, branch []string, last time.Time, kind string, width, height, maxVal int) error {
+func (a *Charts) Get(ctx context.Context, w io.Writer, src source.Source, title string, usernames []string, size int, states []source.PullRequestState, repository, branch []string, mergeStrategies []source.PullRequestMergeMethod, last time.Time, kind string, width, height, maxVal, concurrency int) (err error) {
 	data := render.ChartData{
 		Title:        title,
 		ValueMessage: kind,
@@ -113,89 +159,102 @@ func (a *Charts) Get(ctx context.Context, w io.Writer, title string, usernames [
 		MaxValue:     maxVal,
 	}
 
-	cbs := []source.PullRequestCallback{}
-	if !last.IsZero() {
-		cbs = append(cbs, func(pr *source.PullRequest) bool {
-			return pr.CreatedAt.After(last)
+	usernames, attrs := utils.KeyAttribute(usernames)
+
+	// Count the commits from the history directly if the source has it
+	lister, _ := src.(source.CommitLister)
+
+	results := make([]map[string]int, len(usernames))
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for i, username := range usernames {
+		g.Go(func() (err error) {
+			before, after := window(attrs[username])
+			if lister != nil && kind == KindCommits && len(mergeStrategies) == 0 {
+				results[i], err = commitDays(ctx, lister, username, last, before, after)
+			} else {
+				results[i], err = pullRequestDays(ctx, src, username, size, states, repository, branch, mergeStrategies, last, before, after, kind)
+			}
+			return err
 		})
 	}
-
-	usernames, attrs := utils.KeyAttribute(usernames)
+	err = g.Wait()
+	if err != nil {
+		return err
+	}
 
 	for i, username := range usernames {
-		prs, err := a.source.PullRequests(ctx, username,
-			states,
-			source.IssueOrderFieldCreatedAt, source.OrderDirectionDesc, size,
-			cbs...)
-		if err != nil {
-			return fmt.Errorf("list PullRequests %q: %w", username, err)
-		}
-		if len(prs) == 0 {
-			continue
+		name := username
+		if n := attrs[username]["name"]; n != "" {
+			name = n
 		}
+		appendSeries(&data, name, i, results[i])
+	}
 
-		var before, after time.Time
-		if v := attrs[username]["before"]; v != "" {
-			before, err = utils.ParseTime(v, time.Local)
-			if err != nil {
-				log.Printf("error ParseTime: %s", err)
-			}
-		}
-		if v := attrs[username]["after"]; v != "" {
-			after, err = utils.ParseTime(v, time.Local)
-			if err != nil {
-				log.Printf("error ParseTime: %s", err)
-			}
-		}
+	if len(data.Series) == 0 {
+		return placeholder_render.PlaceHolderRender(w, placeholder_render.PlaceHolderData{
+			Width:  len(title) * 9,
+			Height: 30,
+			Text:   title,
+		})
+	}
+	return render.ChartRender(w, data)
+}
 
-		days := map[string]int{}
-		for _, pr := range prs {
-			if pr.SortTime.Before(last) {
-				continue
-			}
-			if len(branch) != 0 && !utils.Match(branch, pr.BaseRef) {
-				continue
-			}
-			repo := strings.TrimPrefix(strings.Split(pr.URL.Path, "/pull/")[0], "/")
-			if len(repository) != 0 && !utils.Match(repository, repo) {
-				continue
-			}
-			if !before.IsZero() && !pr.SortTime.Before(before) {
-				continue
-			}
-			if !after.IsZero() && !pr.SortTime.After(after) {
-				continue
+// GetScopes renders a series for each scope, only the pull requests of the usernames are counted if any
+func (a *Charts) GetScopes(ctx context.Context, w io.Writer, src source.Source, title string, scopes []source.Scope, usernames []string, size int, states []source.PullRequestState, repository, branch []string, mergeStrategies []source.PullRequestMergeMethod, last time.Time, kind string, width, height, maxVal, concurrency int) (err error) {
+	data := render.ChartData{
+		Title:        title,
+		ValueMessage: kind,
+		Width:        width,
+		Height:       height,
+		MaxValue:     maxVal,
+	}
+
+	usernames, attrs := utils.KeyAttribute(usernames)
+
+	results := make([]map[string]int, len(scopes))
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for i, scope := range scopes {
+		g.Go(func() (err error) {
+			prs, err := src.ScopedPullRequests(ctx, scope,
+				states,
+				source.IssueOrderFieldCreatedAt, source.OrderDirectionDesc, size,
+				sinceCallbacks(last)...)
+			if err != nil {
+				return fmt.Errorf("list PullRequests of %s: %w", scope, err)
 			}
 
-			key := pr.SortTime.Format(render.DateFmt)
-			switch kind {
-			case KindCommits:
-				days[key] = days[key] + pr.Commits
-			case KindPRs:
-				days[key] = days[key] + 1
+			days := map[string]int{}
+			windows := map[string][2]time.Time{}
+			for _, pr := range prs {
+				attr, ok := attrs[pr.Username]
+				if !ok && len(usernames) != 0 {
+					continue
+				}
+				win, ok := windows[pr.Username]
+				if !ok {
+					win[0], win[1] = window(attr)
+					windows[pr.Username] = win
+				}
+				countPullRequest(days, pr, repository, branch, mergeStrategies, last, win[0], win[1], kind)
 			}
-		}
-		if len(days) == 0 {
-			continue
-		}
-		points := make(render.Points, 0, len(days))
-		for date, val := range days {
-			t, _ := time.Parse(render.DateFmt, date)
-			points = append(points, render.Point{
-				Value: val,
-				Time:  t,
-			})
-		}
+			results[i] = days
+			return nil
+		})
+	}
+	err = g.Wait()
+	if err != nil {
+		return err
+	}
 
-		name := username
-		if n := attrs[username]["name"]; n != "" {
-			name = n
+	for i, scope := range scopes {
+		name := scope.String()
+		if scope.Kind == source.ScopeSearch {
+			name = scope.Name
 		}
-		data.Series = append(data.Series, render.Series{
-			Name:   name,
-			Points: points,
-			Index:  i,
-		})
+		appendSeries(&data, name, i, results[i])
 	}
 
 	if len(data.Series) == 0 {
@@ -207,3 +266,131 @@ func (a *Charts) Get(ctx context.Context, w io.Writer, title string, usernames [
 	}
 	return render.ChartRender(w, data)
 }
+
+// appendSeries appends the series of the days if it's not empty
+func appendSeries(data *render.ChartData, name string, index int, days map[string]int) {
+	if len(days) == 0 {
+		return
+	}
+	points := make(render.Points, 0, len(days))
+	for date, val := range days {
+		t, _ := time.Parse(render.DateFmt, date)
+		points = append(points, render.Point{
+			Value: val,
+			Time:  t,
+		})
+	}
+	data.Series = append(data.Series, render.Series{
+		Name:   name,
+		Points: points,
+		Index:  index,
+	})
+}
+
+// window returns the before and after of the attribute of the user
+func window(attr map[string]string) (before, after time.Time) {
+	var err error
+	if v := attr["before"]; v != "" {
+		before, err = utils.ParseTime(v, time.Local)
+		if err != nil {
+			log.Printf("error ParseTime: %s", err)
+		}
+	}
+	if v := attr["after"]; v != "" {
+		after, err = utils.ParseTime(v, time.Local)
+		if err != nil {
+			log.Printf("error ParseTime: %s", err)
+		}
+	}
+	return before, after
+}
+
+// sinceCallbacks stops the listing at the pull requests created before last
+func sinceCallbacks(last time.Time) []source.PullRequestCallback {
+	cbs := []source.PullRequestCallback{}
+	if !last.IsZero() {
+		cbs = append(cbs, func(pr *source.PullRequest) bool {
+			return pr.CreatedAt.After(last)
+		})
+	}
+	return cbs
+}
+
+func pullRequestDays(ctx context.Context, src source.Source, username string, size int, states []source.PullRequestState, repository, branch []string, mergeStrategies []source.PullRequestMergeMethod, last, before, after time.Time, kind string) (map[string]int, error) {
+	days := map[string]int{}
+	listed := 0
+	for pr, err := range source.IterPullRequests(ctx, src, username, states, source.IssueOrderFieldCreatedAt, source.OrderDirectionDesc) {
+		if err != nil {
+			return nil, fmt.Errorf("list PullRequests %q: %w", username, err)
+		}
+		if (size >= 0 && listed >= size) || (!last.IsZero() && !pr.CreatedAt.After(last)) {
+			break
+		}
+		countPullRequest(days, pr, repository, branch, mergeStrategies, last, before, after, kind)
+		listed++
+	}
+	return days, nil
+}
+
+// countPullRequest counts the pull request into the day if it matches the filters
+func countPullRequest(days map[string]int, pr *source.PullRequest, repository, branch []string, mergeStrategies []source.PullRequestMergeMethod, last, before, after time.Time, kind string) {
+	if pr.SortTime.Before(last) {
+		return
+	}
+	if len(branch) != 0 && !utils.Match(branch, pr.BaseRef) {
+		return
+	}
+	if len(repository) != 0 && !utils.Match(repository, pr.Repository) {
+		return
+	}
+	if len(mergeStrategies) != 0 {
+		match := false
+		for _, m := range mergeStrategies {
+			if string(m) == pr.MergeStrategy {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return
+		}
+	}
+	if !before.IsZero() && !pr.SortTime.Before(before) {
+		return
+	}
+	if !after.IsZero() && !pr.SortTime.After(after) {
+		return
+	}
+
+	key := pr.SortTime.Format(render.DateFmt)
+	switch kind {
+	case KindCommits:
+		days[key] = days[key] + pr.Commits
+	case KindPRs:
+		days[key] = days[key] + 1
+	}
+}
+
+func commitDays(ctx context.Context, lister source.CommitLister, username string, last, before, after time.Time) (map[string]int, error) {
+	commits, err := lister.Commits(ctx, username, last)
+	if err != nil {
+		return nil, fmt.Errorf("list Commits %q: %w", username, err)
+	}
+
+	days := map[string]int{}
+	for _, commit := range commits {
+		if commit.When.Before(last) {
+			continue
+		}
+		if !before.IsZero() && !commit.When.Before(before) {
+			continue
+		}
+		if !after.IsZero() && !commit.When.After(after) {
+			continue
+		}
+
+		key := commit.When.Format(render.DateFmt)
+		days[key] = days[key] + 1
+	}
+	return days, nil
+}
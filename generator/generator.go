@@ -3,6 +3,7 @@ package generator
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -10,10 +11,14 @@ import (
 	"github.com/wzshiming/profile_stats/generator/activities"
 	"github.com/wzshiming/profile_stats/generator/charts"
 	"github.com/wzshiming/profile_stats/generator/now"
+	"github.com/wzshiming/profile_stats/generator/orgs"
 	"github.com/wzshiming/profile_stats/generator/placeholder"
+	"github.com/wzshiming/profile_stats/generator/reviews"
 	"github.com/wzshiming/profile_stats/generator/stats"
 	"github.com/wzshiming/profile_stats/source"
+	"github.com/wzshiming/profile_stats/utils"
 	"github.com/wzshiming/xmlinjector"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -21,21 +26,45 @@ const (
 	blankChar = "\n"
 )
 
+// The modes of the placeholder which fails
+const (
+	// OnErrorKeep keeps the previous content, the error is only warned
+	OnErrorKeep = "keep"
+	// OnErrorComment prepends the error comment to the previous content
+	OnErrorComment = "comment"
+	// OnErrorFail aborts the whole document
+	OnErrorFail = "fail"
+)
+
 type Handler struct {
-	registry map[string]profile_stats.Generator
+	registry    map[string]profile_stats.Generator
+	concurrency int
+	// timeout is the default timeout of each placeholder, zero means no timeout
+	timeout time.Duration
+	// onError is the default mode of the placeholder which fails
+	onError string
 }
 
-func NewHandler(src *source.Source) *Handler {
+// NewHandler returns the handler, onError is one of OnErrorKeep, OnErrorComment and OnErrorFail, empty means OnErrorComment
+func NewHandler(sources *source.Registry, timeout time.Duration, onError string) *Handler {
+	if onError == "" {
+		onError = OnErrorComment
+	}
 	r := &Handler{
-		registry: map[string]profile_stats.Generator{},
+		registry:    map[string]profile_stats.Generator{},
+		concurrency: utils.DefaultConcurrency,
+		timeout:     timeout,
+		onError:     onError,
 	}
 
 	r.register("now", now.NewNow())
 	r.register("updatedat", now.NewNow())
 	r.register("placeholder", placeholder.NewPlaceHolder())
-	r.register("activities", activities.NewActivities(src))
-	r.register("stats", stats.NewStats(src))
-	r.register("charts", charts.NewCharts(src))
+	r.register("activities", activities.NewActivities(sources))
+	r.register("stats", stats.NewStats(sources))
+	r.register("charts", charts.NewCharts(sources))
+	r.register("reviews", reviews.NewReviews(sources))
+	r.register("orgs", orgs.NewOrgs(sources))
 	return r
 }
 
@@ -43,52 +72,164 @@ func (r *Handler) register(name string, generator profile_stats.Generator) {
 	r.registry[name] = generator
 }
 
+type block struct {
+	args    []byte
+	origin  []byte
+	result  []byte
+	warning string
+}
+
 func (r *Handler) Handle(ctx context.Context, origin []byte) ([]byte, []string, error) {
-	buf := bytes.NewBuffer(nil)
+	// The first pass collects the placeholders, then they are generated in parallel
+	var blocks []*block
+	_, err := xmlinjector.Inject([]byte(key), origin, func(args, origin []byte) []byte {
+		blocks = append(blocks, &block{
+			args:   bytes.Clone(args),
+			origin: bytes.Clone(origin),
+		})
+		return origin
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The others are canceled once a placeholder fails in the fail mode
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(r.concurrency)
+	for _, b := range blocks {
+		g.Go(func() (err error) {
+			b.result, b.warning, err = r.generate(gctx, b.args, b.origin)
+			return err
+		})
+	}
+	err = g.Wait()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The second pass fills the results of the placeholders in the same order
 	var warnings []string
+	i := 0
 	date, err := xmlinjector.Inject([]byte(key), origin, func(args, origin []byte) []byte {
-		tag := NewArgs(string(args), true)
-		template, ok := tag.String("template")
-		if !ok || template == "" {
-			warnings = append(warnings, fmt.Sprintf("%q: no template", args))
-			return errInfo("no template", origin)
+		b := blocks[i]
+		i++
+		if b.warning != "" {
+			warnings = append(warnings, b.warning)
 		}
+		return b.result
+	})
+	return date, warnings, err
+}
 
-		blank, ok := tag.Int("blank")
-		if !ok {
-			blank = 2
-		}
+// generate returns the content of the placeholder, and the warning if it fails,
+// the error is only returned if it fails in the fail mode.
+func (r *Handler) generate(ctx context.Context, args, origin []byte) ([]byte, string, error) {
+	tag := NewArgs(string(args), true)
+	onError, ok := tag.String("on_error")
+	if !ok || onError == "" {
+		onError = r.onError
+	}
+	switch onError {
+	default:
+		return errInfo(fmt.Sprintf("not support on_error %q", onError), origin), fmt.Sprintf("%q: not support on_error %q", args, onError), nil
+	case OnErrorKeep, OnErrorComment, OnErrorFail:
+	}
 
-		generator, ok := r.registry[template]
-		if !ok {
-			warnings = append(warnings, fmt.Sprintf("%q: not support template %q", args, template))
-			return errInfo(fmt.Sprintf("not support template %q", template), origin)
-		}
-		buf.Reset()
-		err := generator.Generate(ctx, buf, tag)
+	content, err := r.render(ctx, tag)
+	if err == nil {
+		return content, "", nil
+	}
+	warning := fmt.Sprintf("%q: %s", args, err.Error())
+	switch {
+	case onError == OnErrorFail:
+		return nil, warning, fmt.Errorf("%q: %w", args, err)
+	case onError == OnErrorKeep, errors.Is(err, errTimeout):
+		// The previous content is kept
+		return origin, warning, nil
+	}
+	return errInfo(err.Error(), origin), warning, nil
+}
+
+// render returns the content of the placeholder with the blanks around
+func (r *Handler) render(ctx context.Context, tag profile_stats.Args) ([]byte, error) {
+	template, ok := tag.String("template")
+	if !ok || template == "" {
+		return nil, fmt.Errorf("no template")
+	}
+
+	blank, ok := tag.Int("blank")
+	if !ok {
+		blank = 2
+	}
+
+	timeout := r.timeout
+	if v, ok := tag.String("timeout"); ok && v != "" {
+		var err error
+		timeout, err = time.ParseDuration(v)
 		if err != nil {
-			warnings = append(warnings, fmt.Sprintf("%q: %s", args, err.Error()))
-			return errInfo(err.Error(), origin)
+			return nil, fmt.Errorf("timeout: %w", err)
 		}
+	}
+
+	generator, ok := r.registry[template]
+	if !ok {
+		return nil, fmt.Errorf("not support template %q", template)
+	}
+	buf, err := generateTimeout(ctx, generator, tag, timeout)
+	if errors.Is(err, errTimeout) {
+		return nil, fmt.Errorf("%w after %s", errTimeout, timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-		raw := buf.Bytes()
-		raw = bytes.Trim(raw, blankChar)
+	raw := buf.Bytes()
+	raw = bytes.Trim(raw, blankChar)
 
-		var tmp []byte
+	var tmp []byte
 
-		if blank > 0 {
-			tmp = make([]byte, 0, len(raw)+blank*2)
-			blanks := bytes.Repeat([]byte(blankChar), blank)
-			tmp = append(tmp, blanks...)
-			tmp = append(tmp, raw...)
-			tmp = append(tmp, blanks...)
-		} else {
-			tmp = make([]byte, len(raw))
-			copy(tmp, raw)
+	if blank > 0 {
+		tmp = make([]byte, 0, len(raw)+blank*2)
+		blanks := bytes.Repeat([]byte(blankChar), blank)
+		tmp = append(tmp, blanks...)
+		tmp = append(tmp, raw...)
+		tmp = append(tmp, blanks...)
+	} else {
+		tmp = make([]byte, len(raw))
+		copy(tmp, raw)
+	}
+	return tmp, nil
+}
+
+var errTimeout = errors.New("timeout")
+
+// generateTimeout generates the content, and returns errTimeout if it isn't done in the timeout.
+// The generator is left behind after the timeout, so a hanging one doesn't stall the others.
+func generateTimeout(ctx context.Context, generator profile_stats.Generator, args profile_stats.Args, timeout time.Duration) (*bytes.Buffer, error) {
+	if timeout <= 0 {
+		buf := bytes.NewBuffer(nil)
+		return buf, generator.Generate(ctx, buf, args)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	buf := bytes.NewBuffer(nil)
+	go func() {
+		done <- generator.Generate(ctx, buf, args)
+	}()
+	select {
+	case err := <-done:
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, errTimeout
 		}
-		return tmp
-	})
-	return date, warnings, err
+		return buf, err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, errTimeout
+		}
+		return nil, ctx.Err()
+	}
 }
 
 func errInfo(msg string, origin []byte) []byte {
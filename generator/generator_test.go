@@ -0,0 +1,760 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wzshiming/profile_stats"
+	"github.com/wzshiming/profile_stats/source"
+)
+
+var record = flag.Bool("record", false, "record the fixtures and the golden file from the API with GH_TOKEN")
+
+type fakeSource struct {
+	stat    *source.Stat
+	repos   []*source.Repository
+	prs     []*source.PullRequest
+	issues  []*source.Issue
+	reviews []*source.Review
+}
+
+func (f *fakeSource) Stat(ctx context.Context, username string, from, to time.Time) (*source.Stat, error) {
+	// The contributions of GitHub are counted in at most one year
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if !from.IsZero() && to.After(from.AddDate(1, 0, 0)) {
+		return nil, fmt.Errorf("the time window from %s to %s exceeds one year", from.Format(time.DateOnly), to.Format(time.DateOnly))
+	}
+	return f.stat, nil
+}
+
+func (f *fakeSource) LifetimeStat(ctx context.Context, username string) (*source.Stat, error) {
+	stat := *f.stat
+	stat.Commits *= 10
+	return &stat, nil
+}
+
+func (f *fakeSource) Repositories(ctx context.Context, username string) ([]*source.Repository, error) {
+	return f.repos, nil
+}
+
+func (f *fakeSource) OrgStat(ctx context.Context, username string, org string, from, to time.Time) (*source.OrgStat, error) {
+	stat := &source.OrgStat{Name: org, Commits: len(org), PullRequests: 1}
+	if org == "abcd" {
+		stat.LogoURL = "https://avatars.example.com/" + org + "?s=64&v=4"
+	}
+	return stat, nil
+}
+
+func (f *fakeSource) Asset(ctx context.Context, uri string) ([]byte, string, error) {
+	return []byte(uri), "image/png", nil
+}
+
+func (f *fakeSource) PullRequests(ctx context.Context, username string, states []source.PullRequestState, orderField source.IssueOrderField, orderDirection source.OrderDirection, size int, cbs ...source.PullRequestCallback) ([]*source.PullRequest, error) {
+	prs := []*source.PullRequest{}
+	for _, pr := range f.prs {
+		if pr.Username != username {
+			continue
+		}
+		for _, cb := range cbs {
+			if !cb(pr) {
+				return prs, nil
+			}
+		}
+		prs = append(prs, pr)
+	}
+	return prs, nil
+}
+
+func (f *fakeSource) ScopedPullRequests(ctx context.Context, scope source.Scope, states []source.PullRequestState, orderField source.IssueOrderField, orderDirection source.OrderDirection, size int, cbs ...source.PullRequestCallback) ([]*source.PullRequest, error) {
+	prs := []*source.PullRequest{}
+	for _, pr := range f.prs {
+		switch scope.Kind {
+		case source.ScopeSearch:
+			if !strings.Contains(pr.Title, scope.Name) {
+				continue
+			}
+		default:
+			if pr.Repository != scope.Name && !strings.HasPrefix(pr.Repository, scope.Name+"/") {
+				continue
+			}
+		}
+		for _, cb := range cbs {
+			if !cb(pr) {
+				return prs, nil
+			}
+		}
+		prs = append(prs, pr)
+	}
+	return prs, nil
+}
+
+func (f *fakeSource) Issues(ctx context.Context, username string, states []source.IssueState, orderField source.IssueOrderField, orderDirection source.OrderDirection, size int, cbs ...source.IssueCallback) ([]*source.Issue, error) {
+	issues := []*source.Issue{}
+	for _, issue := range f.issues {
+		if issue.Username != username {
+			continue
+		}
+		for _, cb := range cbs {
+			if !cb(issue) {
+				return issues, nil
+			}
+		}
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
+
+func (f *fakeSource) Reviews(ctx context.Context, username string, from, to time.Time, size int, cbs ...source.ReviewCallback) ([]*source.Review, error) {
+	reviews := []*source.Review{}
+	for _, review := range f.reviews {
+		if review.Username != username {
+			continue
+		}
+		for _, cb := range cbs {
+			if !cb(review) {
+				return reviews, nil
+			}
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, nil
+}
+
+func TestHandle(t *testing.T) {
+	now := time.Now()
+	src := &fakeSource{
+		stat: &source.Stat{
+			Stars:   1234,
+			Commits: 56,
+		},
+		repos: []*source.Repository{
+			{Name: "foo/bar", Stars: 1000, Forks: 10},
+			{Name: "foo/fork", Stars: 200, Forks: 3, IsFork: true},
+			{Name: "foo/old", Stars: 34, Forks: 7, IsArchived: true},
+		},
+		prs: []*source.PullRequest{
+			{
+				Username:   "foo",
+				Repository: "foo/bar",
+				Number:     1,
+				Title:      "Fix",
+				URL:        &url.URL{Scheme: "https", Host: "github.com", Path: "/foo/bar/pull/1"},
+				BaseRef:    "master",
+				State:      string(source.PullRequestStateMerged),
+				CreatedAt:  now,
+				MergedAt:   now,
+				UpdatedAt:  now,
+				SortTime:   now,
+
+				Commits:        1,
+				MergeStrategy:  string(source.PullRequestMergeMethodSquash),
+				ReviewDecision: string(source.PullRequestReviewDecisionApproved),
+				Approvers:      []string{"baz"},
+				MergedBy:       "baz",
+				FirstReviewAt:  now.Add(3 * time.Hour),
+			},
+			{
+				Username:   "bar",
+				Repository: "foo/bar",
+				Number:     4,
+				Title:      "Docs",
+				URL:        &url.URL{Scheme: "https", Host: "github.com", Path: "/foo/bar/pull/4"},
+				BaseRef:    "master",
+				State:      string(source.PullRequestStateOpen),
+				CreatedAt:  now,
+				UpdatedAt:  now,
+				SortTime:   now,
+
+				IsDraft:            true,
+				ReviewDecision:     string(source.PullRequestReviewDecisionReviewRequired),
+				RequestedReviewers: []string{"org/docs"},
+				Comments:           5,
+			},
+		},
+		issues: []*source.Issue{
+			{
+				Username:   "foo",
+				Repository: "foo/bar",
+				Number:     2,
+				Title:      "Bug",
+				URL:        &url.URL{Scheme: "https", Host: "github.com", Path: "/foo/bar/issues/2"},
+				State:      string(source.IssueStateOpen),
+				Comments:   3,
+				CreatedAt:  now,
+				UpdatedAt:  now,
+				SortTime:   now,
+			},
+		},
+		reviews: []*source.Review{
+			{
+				Username:    "foo",
+				Repository:  "baz/qux",
+				Number:      3,
+				Title:       "Feature <b>x|y</b> for the long titles which are not wrapped",
+				URL:         &url.URL{Scheme: "https", Host: "github.com", Path: "/baz/qux/pull/3"},
+				BaseRef:     "main",
+				Author:      "bar",
+				State:       string(source.PullRequestReviewStateApproved),
+				SubmittedAt: now,
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		origin   string
+		contains []string
+		excludes []string
+		// warning is the warning of the invalid arguments
+		warning string
+	}{
+		{
+			name:     "activities",
+			origin:   `<!-- PROFILE_STATS template:"activities" username:"foo" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"[foo/bar#1](https://github.com/foo/bar/pull/1)", "master"},
+		},
+		{
+			name:     "activities of repository",
+			origin:   `<!-- PROFILE_STATS template:"activities" scope:"repo:foo/bar" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"[foo/bar#1]", "[foo/bar#4]"},
+		},
+		{
+			name:     "activities of organization by user",
+			origin:   `<!-- PROFILE_STATS template:"activities" scope:"org:foo" username:"bar:name=Bar" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"[foo/bar#4]", "Bar"},
+			excludes: []string{"[foo/bar#1]"},
+		},
+		{
+			name:     "activities of query",
+			origin:   `<!-- PROFILE_STATS template:"activities" query:"Docs" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"[foo/bar#4]"},
+			excludes: []string{"[foo/bar#1]"},
+		},
+		{
+			name:     "activities with columns",
+			origin:   `<!-- PROFILE_STATS template:"activities" scope:"repo:foo/bar" columns:"draft,review_decision,reviewers,approvers,comments,merged_by,first_review" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"Review Decision", "Draft", "Review&nbsp;required", "org/docs", "baz", "3h"},
+		},
+		{
+			name:     "activities of drafts",
+			origin:   `<!-- PROFILE_STATS template:"activities" scope:"repo:foo/bar" draft:"true" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"[foo/bar#4]"},
+			excludes: []string{"[foo/bar#1]"},
+		},
+		{
+			name:     "activities approved by user",
+			origin:   `<!-- PROFILE_STATS template:"activities" scope:"repo:foo/bar" review_decision:"approved" approvers:"baz" merged_by:"baz" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"[foo/bar#1]"},
+			excludes: []string{"[foo/bar#4]"},
+		},
+		{
+			name:     "activities of requested reviewers",
+			origin:   `<!-- PROFILE_STATS template:"activities" scope:"repo:foo/bar" reviewers:"org/*" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"[foo/bar#4]"},
+			excludes: []string{"[foo/bar#1]"},
+		},
+		{
+			name:     "activities squashed",
+			origin:   `<!-- PROFILE_STATS template:"activities" scope:"repo:foo/bar" merge_strategy:"squash" columns:"merge_strategy" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"[foo/bar#1]", "Merge Strategy", "Squash"},
+			excludes: []string{"[foo/bar#4]"},
+		},
+		{
+			name:     "charts of rebased",
+			origin:   `<!-- PROFILE_STATS template:"charts" kind:"commits" scope:"repo:foo/bar" merge_strategy:"rebase" --><!-- /PROFILE_STATS -->`,
+			excludes: []string{">repo:foo/bar<"},
+		},
+		{
+			name:     "charts of squashed",
+			origin:   `<!-- PROFILE_STATS template:"charts" kind:"commits" scope:"repo:foo/bar" merge_strategy:"squash" --><!-- /PROFILE_STATS -->`,
+			contains: []string{">repo:foo/bar<"},
+		},
+		{
+			name:     "charts of query",
+			origin:   `<!-- PROFILE_STATS template:"charts" kind:"prs" query:"Fix" --><!-- /PROFILE_STATS -->`,
+			contains: []string{">Fix<"},
+		},
+		{
+			name:     "charts of repository",
+			origin:   `<!-- PROFILE_STATS template:"charts" kind:"prs" scope:"repo:foo/bar" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"repo:foo/bar"},
+		},
+		{
+			name:     "issues",
+			origin:   `<!-- PROFILE_STATS template:"activities" type:"issues" username:"foo" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"[foo/bar#2](https://github.com/foo/bar/issues/2)", "| Comments |"},
+		},
+		{
+			name:     "prs and issues",
+			origin:   `<!-- PROFILE_STATS template:"activities" type:"prs,issues" username:"foo" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"[foo/bar#1](https://github.com/foo/bar/pull/1)", "[foo/bar#2](https://github.com/foo/bar/issues/2)", "3 comments"},
+		},
+		{
+			name:    "merged issues",
+			origin:  `<!-- PROFILE_STATS template:"activities" type:"issues" states:"merged" username:"foo" --><!-- /PROFILE_STATS -->`,
+			warning: `can't support type "issues" with states "merged"`,
+		},
+		{
+			name:     "reviews",
+			origin:   `<!-- PROFILE_STATS template:"reviews" username:"foo" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"[baz/qux#3](https://github.com/baz/qux/pull/3) | Feature &lt;b&gt;x&#124;y&lt;/b&gt; for the long titles which are not wrapped |", "Approved<br/>", "| bar "},
+		},
+		{
+			name:     "stats",
+			origin:   `<!-- PROFILE_STATS template:"stats" username:"foo" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"1,234", "56", "Commits in a year"},
+		},
+		{
+			name:     "stats in a calendar year",
+			origin:   `<!-- PROFILE_STATS template:"stats" username:"foo" from:"2023-01-01" to:"2023-12-31" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"Commits in 2023"},
+		},
+		{
+			name:     "stats in the last days",
+			origin:   `<!-- PROFILE_STATS template:"stats" username:"foo" span:"30days" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"Commits in the last 30days"},
+		},
+		{
+			name:     "stats in the last year",
+			origin:   `<!-- PROFILE_STATS template:"stats" username:"foo" span:"1years" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"in the last 1years"},
+		},
+		{
+			name:     "stats of all time",
+			origin:   `<!-- PROFILE_STATS template:"stats" username:"foo" period:"all" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"560", "Commits of all time"},
+		},
+		{
+			name:     "orgs",
+			origin:   `<!-- PROFILE_STATS template:"orgs" username:"foo" orgs:"ab,abcd" --><!-- /PROFILE_STATS -->`,
+			contains: []string{"<svg", ">abcd<", ">Commits<", `href="data:image/png;base64,aHR0cHM6Ly9hdmF0YXJzLmV4YW1wbGUuY29tL2FiY2Q/cz02NCZ2PTQ="`},
+		},
+		{
+			name:     "orgs in markdown",
+			origin:   `<!-- PROFILE_STATS template:"orgs" username:"foo" orgs:"ab,abcd" format:"markdown" sort:"name" --><!-- /PROFILE_STATS -->`,
+			contains: []string{" Organization ", "| ab ", `| <img src="https://avatars.example.com/abcd?s=64&amp;v=4" width="16" height="16"/> abcd `},
+		},
+		{
+			name:     "stats without forks and archived",
+			origin:   `<!-- PROFILE_STATS template:"stats" username:"foo" include_forks:"false" include_archived:"false" --><!-- /PROFILE_STATS -->`,
+			contains: []string{">1,000<", ">10<"},
+		},
+		{
+			name:     "stats of repositories",
+			origin:   `<!-- PROFILE_STATS template:"stats" username:"foo" repository:"foo/old" --><!-- /PROFILE_STATS -->`,
+			contains: []string{">34<", ">7<"},
+		},
+	}
+	sources := source.NewRegistry("fake")
+	sources.Register("fake", func(baseURL string) (source.Source, error) {
+		return src, nil
+	})
+	handler := NewHandler(sources, 0, "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := handler.Handle(context.Background(), []byte(tt.origin))
+			if err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if tt.warning != "" {
+				if len(warnings) != 1 || !strings.Contains(warnings[0], tt.warning) {
+					t.Fatalf("Handle() warnings = %v, want %q", warnings, tt.warning)
+				}
+			} else if len(warnings) != 0 {
+				t.Fatalf("Handle() warnings = %v", warnings)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("Handle() = %s, want contains %q", got, want)
+				}
+			}
+			for _, want := range tt.excludes {
+				if strings.Contains(string(got), want) {
+					t.Errorf("Handle() = %s, want excludes %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleChartsByUser(t *testing.T) {
+	now := time.Now()
+	newPullRequest := func(number int, created time.Time) *source.PullRequest {
+		return &source.PullRequest{
+			Username:   "foo",
+			Repository: "foo/bar",
+			Number:     number,
+			URL:        &url.URL{Scheme: "https", Host: "github.com", Path: fmt.Sprintf("/foo/bar/pull/%d", number)},
+			State:      string(source.PullRequestStateOpen),
+			CreatedAt:  created,
+			UpdatedAt:  created,
+			SortTime:   created,
+		}
+	}
+	src := &fakeSource{
+		prs: []*source.PullRequest{
+			newPullRequest(3, now),
+			newPullRequest(2, now),
+			// It's out of the default span, where the listing stops
+			newPullRequest(1, now.AddDate(-2, 0, 0)),
+		},
+	}
+	sources := source.NewRegistry("fake")
+	sources.Register("fake", func(baseURL string) (source.Source, error) {
+		return src, nil
+	})
+	handler := NewHandler(sources, 0, "")
+
+	for origin, want := range map[string]string{
+		`<!-- PROFILE_STATS template:"charts" kind:"prs" username:"foo" --><!-- /PROFILE_STATS -->`:          `text-anchor:middle" >2<`,
+		`<!-- PROFILE_STATS template:"charts" kind:"prs" username:"foo" size:"1" --><!-- /PROFILE_STATS -->`: `text-anchor:middle" >1<`,
+	} {
+		got, warnings, err := handler.Handle(context.Background(), []byte(origin))
+		if err != nil || len(warnings) != 0 {
+			t.Fatalf("Handle() error = %v, warnings = %v", err, warnings)
+		}
+		if !strings.Contains(string(got), want) {
+			t.Errorf("Handle(%s) = %s, want contains %q", origin, got, want)
+		}
+	}
+}
+
+// blockGenerator blocks until it's released, or until the context is done if it's not stubborn
+type blockGenerator struct {
+	stubborn bool
+	release  chan struct{}
+}
+
+func (g *blockGenerator) Generate(ctx context.Context, w io.Writer, args profile_stats.Args) error {
+	if g.stubborn {
+		<-g.release
+		return nil
+	}
+	select {
+	case <-g.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestHandleTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	handler := NewHandler(source.NewRegistry(""), 10*time.Millisecond, "")
+	handler.register("block", &blockGenerator{release: release})
+	handler.register("stubborn", &blockGenerator{stubborn: true, release: release})
+
+	origin := `<!-- PROFILE_STATS template:"block" -->old block<!-- /PROFILE_STATS -->` +
+		`<!-- PROFILE_STATS template:"stubborn" timeout:"20ms" -->old stubborn<!-- /PROFILE_STATS -->` +
+		`<!-- PROFILE_STATS template:"placeholder" text:"text" --><!-- /PROFILE_STATS -->` +
+		`<!-- PROFILE_STATS template:"placeholder" text:"text" timeout:"soon" --><!-- /PROFILE_STATS -->`
+	got, warnings, err := handler.Handle(context.Background(), []byte(origin))
+	if err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	for _, want := range []string{"-->old block<!--", "-->old stubborn<!--", ">text<"} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("Handle() = %s, want contains %q", got, want)
+		}
+	}
+	want := []string{"timeout after 10ms", "timeout after 20ms", "timeout: time: invalid duration"}
+	if len(warnings) != len(want) {
+		t.Fatalf("Handle() warnings = %v, want %v", warnings, want)
+	}
+	for i, warning := range warnings {
+		if !strings.Contains(warning, want[i]) {
+			t.Errorf("Handle() warning = %q, want contains %q", warning, want[i])
+		}
+	}
+}
+
+type failGenerator struct{}
+
+func (failGenerator) Generate(ctx context.Context, w io.Writer, args profile_stats.Args) error {
+	return fmt.Errorf("api unavailable")
+}
+
+func TestHandleOnError(t *testing.T) {
+	block := `<!-- PROFILE_STATS template:"fail"%s -->old<!-- /PROFILE_STATS -->`
+	tests := []struct {
+		name     string
+		onError  string
+		arg      string
+		want     string
+		warnings int
+		wantErr  bool
+	}{
+		{
+			name:     "comment by default",
+			want:     "profile_stats_error error:\"api unavailable\"",
+			warnings: 1,
+		},
+		{
+			name:     "keep",
+			onError:  OnErrorKeep,
+			want:     fmt.Sprintf(block, ""),
+			warnings: 1,
+		},
+		{
+			name:    "fail",
+			onError: OnErrorFail,
+			wantErr: true,
+		},
+		{
+			name:     "keep by argument",
+			onError:  OnErrorFail,
+			arg:      ` on_error:"keep"`,
+			want:     fmt.Sprintf(block, ` on_error:"keep"`),
+			warnings: 1,
+		},
+		{
+			name:     "unknown mode",
+			arg:      ` on_error:"retry"`,
+			want:     "not support on_error",
+			warnings: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(source.NewRegistry(""), 0, tt.onError)
+			handler.register("fail", failGenerator{})
+			got, warnings, err := handler.Handle(context.Background(), []byte(fmt.Sprintf(block, tt.arg)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Handle() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !strings.Contains(string(got), tt.want) {
+				t.Errorf("Handle() = %s, want contains %q", got, tt.want)
+			}
+			if len(warnings) != tt.warnings {
+				t.Errorf("Handle() warnings = %v, want %d", warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestHandleInOrder(t *testing.T) {
+	handler := NewHandler(source.NewRegistry(""), 0, "")
+	origin := ""
+	for i := 0; i != 10; i++ {
+		origin += fmt.Sprintf(`<!-- PROFILE_STATS template:"placeholder" text:"text%d" --><!-- /PROFILE_STATS -->`, i)
+	}
+	origin += `<!-- PROFILE_STATS template:"unknown" --><!-- /PROFILE_STATS -->`
+	got, warnings, err := handler.Handle(context.Background(), []byte(origin))
+	if err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("Handle() warnings = %v, want 1", warnings)
+	}
+	last := -1
+	for i := 0; i != 10; i++ {
+		index := strings.Index(string(got), fmt.Sprintf(">text%d<", i))
+		if index <= last {
+			t.Fatalf("Handle() = %s, want text%d in order", got, i)
+		}
+		last = index
+	}
+}
+
+// TestHandleGolden replays the fixtures, which are synthetic until they're recorded with -record,
+// so their shapes are checked against the queries by TestGoldenFixtures.
+func TestHandleGolden(t *testing.T) {
+	// The time windows of the queries are in local time
+	local := time.Local
+	time.Local = time.UTC
+	defer func() {
+		time.Local = local
+	}()
+
+	sources := source.NewRegistry("github")
+	sources.Register("github", func(baseURL string) (source.Source, error) {
+		return source.NewGitHub("", os.Getenv("GH_TOKEN"), source.CachePolicy{
+			Fixtures: "testdata/fixtures",
+			Record:   *record,
+		}, 0, 0)
+	})
+	origin, err := os.ReadFile("testdata/README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, warnings, err := NewHandler(sources, 0, "").Handle(context.Background(), origin)
+	if err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("Handle() warnings = %v", warnings)
+	}
+
+	if *record {
+		err = os.WriteFile("testdata/README.golden.md", got, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile("testdata/README.golden.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Handle() = %s, want %s", got, want)
+	}
+}
+
+// TestGoldenFixtures checks that the responses of the fixtures have exactly the fields selected by their queries,
+// the queries are built from the query structs as the name of a fixture is the hash of the request.
+func TestGoldenFixtures(t *testing.T) {
+	names, err := filepath.Glob("testdata/fixtures/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fx struct {
+			Body     string `json:"body"`
+			Response string `json:"response"`
+		}
+		err = json.Unmarshal(data, &fx)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var body struct {
+			Query string `json:"query"`
+		}
+		err = json.Unmarshal([]byte(fx.Body), &body)
+		if err != nil {
+			t.Fatalf("%s body: %v", name, err)
+		}
+		var resp struct {
+			Data   interface{}   `json:"data"`
+			Errors []interface{} `json:"errors"`
+		}
+		err = json.Unmarshal([]byte(fx.Response), &resp)
+		if err != nil {
+			t.Fatalf("%s response: %v", name, err)
+		}
+		if len(resp.Errors) != 0 {
+			t.Errorf("%s: response has errors %v", name, resp.Errors)
+		}
+
+		query := body.Query[strings.Index(body.Query, "{"):]
+		sel, rest := parseSelection(query)
+		if rest != "" {
+			t.Fatalf("%s: can't parse the query at %q", name, rest)
+		}
+		for _, err := range sel.check("data", resp.Data) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+// selection is a selection set of a GraphQL query
+type selection struct {
+	fields []string
+	sub    map[string]*selection
+	// fragments are the inline fragments, their fields are only in the objects of the types
+	fragments []*selection
+}
+
+// parseSelection parses the selection set at the start of the query, it returns the rest of the query
+func parseSelection(query string) (*selection, string) {
+	sel := &selection{sub: map[string]*selection{}}
+	if !strings.HasPrefix(query, "{") {
+		return sel, query
+	}
+	query = query[1:]
+	for query != "" && query[0] != '}' {
+		if query[0] == ',' {
+			query = query[1:]
+			continue
+		}
+		if strings.HasPrefix(query, "... on ") {
+			query = strings.TrimLeft(query[len("... on "):], "_0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+			var fragment *selection
+			fragment, query = parseSelection(query)
+			sel.fragments = append(sel.fragments, fragment)
+			continue
+		}
+		end := strings.IndexAny(query, "(,{}")
+		if end <= 0 {
+			return sel, query
+		}
+		field := query[:end]
+		query = query[end:]
+		if query[0] == '(' {
+			// The arguments may have the objects, but not the parentheses
+			query = query[strings.Index(query, ")")+1:]
+		}
+		sel.fields = append(sel.fields, field)
+		if strings.HasPrefix(query, "{") {
+			sel.sub[field], query = parseSelection(query)
+		}
+	}
+	return sel, strings.TrimPrefix(query, "}")
+}
+
+// check returns the differences of the value from the selection
+func (s *selection) check(path string, value interface{}) []error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		var errs []error
+		for i, item := range v {
+			errs = append(errs, s.check(fmt.Sprintf("%s[%d]", path, i), item)...)
+		}
+		return errs
+	case map[string]interface{}:
+		var errs []error
+		selected := map[string]*selection{}
+		for _, field := range s.fields {
+			selected[field] = s.sub[field]
+			item, ok := v[field]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: missing %q", path, field))
+				continue
+			}
+			if sub := s.sub[field]; sub != nil {
+				errs = append(errs, sub.check(path+"."+field, item)...)
+			}
+		}
+		for _, fragment := range s.fragments {
+			for _, field := range fragment.fields {
+				selected[field] = fragment.sub[field]
+				if item, ok := v[field]; ok && fragment.sub[field] != nil {
+					errs = append(errs, fragment.sub[field].check(path+"."+field, item)...)
+				}
+			}
+		}
+		for field := range v {
+			if _, ok := selected[field]; !ok {
+				errs = append(errs, fmt.Errorf("%s: unselected %q", path, field))
+			}
+		}
+		return errs
+	default:
+		if len(s.fields) != 0 {
+			return []error{fmt.Errorf("%s: %v is not an object", path, value)}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,178 @@
+package orgs
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wzshiming/profile_stats"
+	"github.com/wzshiming/profile_stats/generator/orgs/render"
+	"github.com/wzshiming/profile_stats/source"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const (
+	FormatSVG      = "svg"
+	FormatMarkdown = "markdown"
+)
+
+const (
+	SortCommits = "commits"
+	SortPRs     = "prs"
+	SortReviews = "reviews"
+	SortIssues  = "issues"
+	SortTotal   = "total"
+	SortName    = "name"
+)
+
+type Orgs struct {
+	sources *source.Registry
+}
+
+func NewOrgs(sources *source.Registry) *Orgs {
+	return &Orgs{
+		sources: sources,
+	}
+}
+
+func (o *Orgs) Generate(ctx context.Context, w io.Writer, args profile_stats.Args) (err error) {
+	username, ok := args.String("username")
+	if !ok || username == "" {
+		return fmt.Errorf("no username")
+	}
+
+	orgs, ok := args.StringSlice("orgs")
+	if !ok || len(orgs) == 0 {
+		return fmt.Errorf("no orgs")
+	}
+
+	title, ok := args.String("title")
+	if !ok {
+		title = username + "'s Contributions in a year"
+	}
+
+	format, ok := args.String("format")
+	if !ok || format == "" {
+		format = FormatSVG
+	}
+	format = strings.ToLower(format)
+	switch format {
+	default:
+		return fmt.Errorf("can't support format %q", format)
+	case FormatSVG, FormatMarkdown:
+	}
+
+	sortBy, ok := args.String("sort")
+	if !ok || sortBy == "" {
+		sortBy = SortCommits
+	}
+	sortBy = strings.ToLower(sortBy)
+	switch sortBy {
+	default:
+		return fmt.Errorf("can't support sort %q", sortBy)
+	case SortCommits, SortPRs, SortReviews, SortIssues, SortTotal, SortName:
+	}
+
+	provider, _ := args.String("provider")
+	baseURL, _ := args.String("base_url")
+	src, err := o.sources.Get(provider, baseURL)
+	if err != nil {
+		return err
+	}
+
+	return o.Get(ctx, w, src, title, username, orgs, format, sortBy)
+}
+
+func (o *Orgs) Get(ctx context.Context, w io.Writer, src source.Source, title, username string, orgs []string, format, sortBy string) error {
+	stats := make([]*source.OrgStat, 0, len(orgs))
+	for _, org := range orgs {
+		stat, err := src.OrgStat(ctx, username, org, time.Time{}, time.Time{})
+		if err != nil {
+			return fmt.Errorf("organization stat %q: %w", org, err)
+		}
+		stats = append(stats, stat)
+	}
+
+	sortOrgStats(stats, sortBy)
+
+	data := render.OrgsData{
+		Title: title,
+		Keys:  []string{"Commits", "PRs", "Reviews", "Issues"},
+		Items: make([]render.OrgsItem, 0, len(stats)),
+	}
+	for _, stat := range stats {
+		item := render.OrgsItem{
+			Name:    stat.Name,
+			LogoURL: stat.LogoURL,
+			Values: []string{
+				formatInt(stat.Commits),
+				formatInt(stat.PullRequests),
+				formatInt(stat.Reviews),
+				formatInt(stat.Issues),
+			},
+		}
+		data.Items = append(data.Items, item)
+	}
+
+	if format == FormatMarkdown {
+		return render.OrgsMarkdownRender(w, data)
+	}
+
+	// The images in the svg can't load the remote resources, so the logos are embedded,
+	// they're fetched through the source to be cached and replayed like the queries
+	fetcher, ok := src.(source.AssetFetcher)
+	if !ok {
+		return render.OrgsRender(w, data)
+	}
+	for i, item := range data.Items {
+		if item.LogoURL == "" {
+			continue
+		}
+		logo, contentType, err := fetcher.Asset(ctx, item.LogoURL)
+		if err != nil {
+			if errors.Is(err, source.ErrNotSupported) {
+				continue
+			}
+			return fmt.Errorf("organization logo %q: %w", item.Name, err)
+		}
+		data.Items[i].LogoData = "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(logo)
+	}
+	return render.OrgsRender(w, data)
+}
+
+func sortOrgStats(stats []*source.OrgStat, sortBy string) {
+	if sortBy == SortName {
+		sort.SliceStable(stats, func(i, j int) bool {
+			return strings.ToLower(stats[i].Name) < strings.ToLower(stats[j].Name)
+		})
+		return
+	}
+	value := func(stat *source.OrgStat) int {
+		switch sortBy {
+		case SortPRs:
+			return stat.PullRequests
+		case SortReviews:
+			return stat.Reviews
+		case SortIssues:
+			return stat.Issues
+		case SortTotal:
+			return stat.Commits + stat.PullRequests + stat.Reviews + stat.Issues
+		}
+		return stat.Commits
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		return value(stats[i]) > value(stats[j])
+	})
+}
+
+var fmtEn = message.NewPrinter(language.English)
+
+func formatInt(i int) string {
+	return fmtEn.Sprint(i)
+}
@@ -0,0 +1,110 @@
+package render
+
+import (
+	"embed"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"text/template"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/wzshiming/profile_stats/render"
+)
+
+var (
+	orgsTemplate *template.Template
+)
+
+//go:embed layouts
+//go:embed themes
+var resource embed.FS
+
+func init() {
+	var err error
+	orgsTemplate, err = template.New("_").
+		Funcs(render.Funcs).
+		ParseFS(resource, "layouts/*.svg", "themes/*.css")
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+type OrgsData struct {
+	Layout              string
+	Theme               string
+	CSS                 string
+	Title               string
+	TitleColor          string
+	DarkTitleColor      string
+	KeyColor            string
+	DarkKeyColor        string
+	ValueColor          string
+	DarkValueColor      string
+	IconColor           string
+	DarkIconColor       string
+	BackgroundColor     string
+	DarkBackgroundColor string
+	StrokeColor         string
+	DarkStrokeColor     string
+	Keys                []string
+	Items               []OrgsItem
+}
+
+type OrgsItem struct {
+	Name     string
+	LogoURL  string
+	LogoData string
+	Values   []string
+}
+
+func OrgsRender(w io.Writer, data OrgsData) error {
+	if data.Theme == "" {
+		data.Theme = "default"
+	}
+	if data.Layout == "" {
+		data.Layout = "default"
+	}
+
+	if data.CSS == "" {
+		buf := render.GetBuffer()
+		err := orgsTemplate.ExecuteTemplate(buf, data.Theme+".css", data)
+		if err != nil {
+			render.PutBuffer(buf)
+			return err
+		}
+		data.CSS = buf.String()
+		render.PutBuffer(buf)
+	}
+
+	data.Title = html.EscapeString(data.Title)
+	items := make([]OrgsItem, 0, len(data.Items))
+	for _, item := range data.Items {
+		item.Name = html.EscapeString(item.Name)
+		items = append(items, item)
+	}
+	data.Items = items
+
+	w = render.NewCompressedSpacesWriter(w)
+	return orgsTemplate.ExecuteTemplate(w, data.Layout+".svg", data)
+}
+
+func OrgsMarkdownRender(w io.Writer, data OrgsData) error {
+	t := make([][]string, 0, len(data.Items))
+	for _, item := range data.Items {
+		name := item.Name
+		if item.LogoURL != "" {
+			name = fmt.Sprintf(`<img src="%s" width="16" height="16"/> %s`, html.EscapeString(item.LogoURL), item.Name)
+		}
+		t = append(t, append([]string{name}, item.Values...))
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoWrapText(false)
+	table.SetHeader(append([]string{"Organization"}, data.Keys...))
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.AppendBulk(t)
+	table.Render()
+	return nil
+}
@@ -0,0 +1,65 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/wzshiming/profile_stats/source"
+)
+
+type ReviewsData struct {
+	Items []ReviewsItem
+}
+
+type ReviewsItem struct {
+	URL         string
+	Username    string
+	Author      string
+	Link        string
+	Title       string
+	BaseRef     string
+	State       string
+	SubmittedAt time.Time
+}
+
+func ReviewsRender(w io.Writer, data ReviewsData) error {
+	t := make([][]string, 0, len(data.Items))
+	for _, item := range data.Items {
+		state := item.State
+		switch state {
+		case string(source.PullRequestReviewStateApproved):
+			state = "Approved"
+		case string(source.PullRequestReviewStateChangesRequested):
+			state = "Changes&nbsp;requested"
+		case string(source.PullRequestReviewStateCommented):
+			state = "Commented"
+		case string(source.PullRequestReviewStateDismissed):
+			state = "Dismissed"
+		}
+		state = fmt.Sprintf("%s<br/>%s", state, formatTime(item.SubmittedAt))
+
+		link := fmt.Sprintf("[%s](%s)", item.Link, item.URL)
+		// The title is written by the author, so it can't break the table or the page
+		title := strings.ReplaceAll(html.EscapeString(item.Title), "|", "&#124;")
+		t = append(t, []string{
+			link, title, item.BaseRef, state, item.Username, item.Author,
+		})
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader([]string{"Link", "Title", "Branch", "Review", "Reviewer", "Author"})
+	table.SetAutoWrapText(false)
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.AppendBulk(t)
+	table.Render()
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	return t.Local().Format("2006-01-02")
+}
@@ -0,0 +1,168 @@
+package reviews
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wzshiming/profile_stats"
+	"github.com/wzshiming/profile_stats/generator/reviews/render"
+	"github.com/wzshiming/profile_stats/source"
+	"github.com/wzshiming/profile_stats/utils"
+)
+
+type Reviews struct {
+	sources *source.Registry
+}
+
+func NewReviews(sources *source.Registry) *Reviews {
+	return &Reviews{
+		sources: sources,
+	}
+}
+
+func (r *Reviews) Generate(ctx context.Context, w io.Writer, args profile_stats.Args) (err error) {
+	usernames, ok := args.StringSlice("username")
+	if !ok {
+		return fmt.Errorf("no usernames")
+	}
+
+	size, ok := args.Int("size")
+	if !ok {
+		size = -1
+	}
+
+	var last time.Time
+	span, ok := args.String("span")
+	if !ok {
+		span = "1years"
+	}
+	if span != "" {
+		now := time.Now()
+		last, err = utils.ParseTimeSpan(span, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	repository, _ := args.StringSlice("repository")
+	branch, _ := args.StringSlice("branch")
+
+	var states []source.PullRequestReviewState
+	statesSlice, ok := args.StringSlice("states")
+	if ok {
+		states = make([]source.PullRequestReviewState, 0, len(statesSlice))
+		for _, state := range statesSlice {
+			s := source.PullRequestReviewState(strings.ToUpper(state))
+			switch s {
+			default:
+				return fmt.Errorf("can't support %q", state)
+			case source.PullRequestReviewStateApproved, source.PullRequestReviewStateChangesRequested,
+				source.PullRequestReviewStateCommented, source.PullRequestReviewStateDismissed:
+			}
+			states = append(states, s)
+		}
+	}
+
+	provider, _ := args.String("provider")
+	baseURL, _ := args.String("base_url")
+	src, err := r.sources.Get(provider, baseURL)
+	if err != nil {
+		return err
+	}
+
+	return r.Get(ctx, w, src, usernames, size, states, repository, branch, last)
+}
+
+func (r *Reviews) Get(ctx context.Context, w io.Writer, src source.Source, usernames []string, size int, states []source.PullRequestReviewState, repository, branch []string, last time.Time) error {
+	items := []*source.Review{}
+
+	usernames, attrs := utils.KeyAttribute(usernames)
+
+	for _, username := range usernames {
+		reviews, err := src.Reviews(ctx, username, last, time.Time{}, size)
+		if err != nil {
+			return fmt.Errorf("list Reviews %q: %w", username, err)
+		}
+
+		var before, after time.Time
+		if v := attrs[username]["before"]; v != "" {
+			before, err = utils.ParseTime(v, time.Local)
+			if err != nil {
+				log.Printf("error ParseTime: %s", err)
+			}
+		}
+		if v := attrs[username]["after"]; v != "" {
+			after, err = utils.ParseTime(v, time.Local)
+			if err != nil {
+				log.Printf("error ParseTime: %s", err)
+			}
+		}
+
+		for _, review := range reviews {
+			if review.SubmittedAt.Before(last) {
+				continue
+			}
+			if len(states) != 0 {
+				match := false
+				for _, state := range states {
+					if string(state) == review.State {
+						match = true
+						break
+					}
+				}
+				if !match {
+					continue
+				}
+			}
+			if len(branch) != 0 && !utils.Match(branch, review.BaseRef) {
+				continue
+			}
+			if len(repository) != 0 && !utils.Match(repository, review.Repository) {
+				continue
+			}
+			if !before.IsZero() && !review.SubmittedAt.Before(before) {
+				continue
+			}
+			if !after.IsZero() && !review.SubmittedAt.After(after) {
+				continue
+			}
+
+			if n := attrs[username]["name"]; n != "" {
+				review.Username = n
+			}
+			items = append(items, review)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].SubmittedAt.After(items[j].SubmittedAt)
+	})
+	data := render.ReviewsData{
+		Items: formatSourceReviews(items),
+	}
+
+	return render.ReviewsRender(w, data)
+}
+
+func formatSourceReviews(reviews []*source.Review) []render.ReviewsItem {
+	items := make([]render.ReviewsItem, 0, len(reviews))
+	for _, review := range reviews {
+		item := render.ReviewsItem{
+			URL:         review.URL.String(),
+			Username:    review.Username,
+			Author:      review.Author,
+			Link:        fmt.Sprintf("%s#%d", review.Repository, review.Number),
+			Title:       review.Title,
+			BaseRef:     review.BaseRef,
+			State:       review.State,
+			SubmittedAt: review.SubmittedAt,
+		}
+		items = append(items, item)
+	}
+	return items
+}
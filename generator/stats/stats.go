@@ -4,25 +4,28 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strconv"
+	"time"
 
 	"github.com/wzshiming/profile_stats"
 	"github.com/wzshiming/profile_stats/generator/stats/render"
 	"github.com/wzshiming/profile_stats/source"
+	"github.com/wzshiming/profile_stats/utils"
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
 )
 
 type Stats struct {
-	source *source.Source
+	sources *source.Registry
 }
 
-func NewStats(src *source.Source) *Stats {
+func NewStats(sources *source.Registry) *Stats {
 	return &Stats{
-		source: src,
+		sources: sources,
 	}
 }
 
-func (s *Stats) Generate(ctx context.Context, w io.Writer, args profile_stats.Args) error {
+func (s *Stats) Generate(ctx context.Context, w io.Writer, args profile_stats.Args) (err error) {
 	username, ok := args.String("username")
 	if !ok || username == "" {
 		return fmt.Errorf("no username")
@@ -33,22 +36,153 @@ func (s *Stats) Generate(ctx context.Context, w io.Writer, args profile_stats.Ar
 		title = username + "'s Stats"
 	}
 
-	return s.Get(ctx, w, title, username)
+	lifetime := false
+	if v, ok := args.String("period"); ok && v != "" {
+		if v != "all" {
+			return fmt.Errorf("can't support period %q", v)
+		}
+		lifetime = true
+	}
+
+	var from, to time.Time
+	period := "in a year"
+	if span, ok := args.String("span"); ok && span != "" {
+		// The contributions can't be counted in more than one year, so the span isn't aligned to the month
+		// and it ends at the same time
+		to = time.Now()
+		from, err = utils.ParseExactTimeSpan(span, to)
+		if err != nil {
+			return err
+		}
+		period = "in the last " + span
+	}
+	if v, ok := args.String("from"); ok && v != "" {
+		from, err = utils.ParseTime(v, time.Local)
+		if err != nil {
+			return err
+		}
+		period = "since " + v
+	}
+	if v, ok := args.String("to"); ok && v != "" {
+		to, err = utils.ParseTime(v, time.Local)
+		if err != nil {
+			return err
+		}
+		// The date includes the whole day or month
+		switch len(v) {
+		case len(time.DateOnly):
+			to = to.AddDate(0, 0, 1)
+		case len("2006-01"):
+			to = to.AddDate(0, 1, 0)
+		}
+		switch {
+		case from.IsZero():
+			period = "in a year until " + v
+		case isYear(from, to):
+			period = "in " + strconv.Itoa(from.Year())
+		default:
+			period = "from " + from.Format(time.DateOnly) + " to " + v
+		}
+	}
+	if lifetime {
+		if !from.IsZero() || !to.IsZero() {
+			return fmt.Errorf("period all can't be used with span, from or to")
+		}
+		period = "of all time"
+	}
+
+	filter := repositoryFilter{
+		includeForks:    true,
+		includeArchived: true,
+	}
+	if v, ok := args.String("include_forks"); ok && v != "" {
+		filter.includeForks, err = strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("include_forks: %w", err)
+		}
+	}
+	if v, ok := args.String("include_archived"); ok && v != "" {
+		filter.includeArchived, err = strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("include_archived: %w", err)
+		}
+	}
+	filter.repository, _ = args.StringSlice("repository")
+
+	provider, _ := args.String("provider")
+	baseURL, _ := args.String("base_url")
+	src, err := s.sources.Get(provider, baseURL)
+	if err != nil {
+		return err
+	}
+
+	return s.Get(ctx, w, src, title, username, from, to, lifetime, period, filter)
 }
 
-func (s *Stats) Get(ctx context.Context, w io.Writer, title, username string) error {
-	stat, err := s.source.Stat(ctx, username)
+// repositoryFilter selects the repositories counted in the total stars and forks
+type repositoryFilter struct {
+	includeForks    bool
+	includeArchived bool
+	repository      []string
+}
+
+func (f repositoryFilter) isDefault() bool {
+	return f.includeForks && f.includeArchived && len(f.repository) == 0
+}
+
+func (f repositoryFilter) match(repo *source.Repository) bool {
+	if !f.includeForks && repo.IsFork {
+		return false
+	}
+	if !f.includeArchived && repo.IsArchived {
+		return false
+	}
+	if len(f.repository) != 0 && !utils.Match(f.repository, repo.Name) {
+		return false
+	}
+	return true
+}
+
+func (s *Stats) Get(ctx context.Context, w io.Writer, src source.Source, title, username string, from, to time.Time, lifetime bool, period string, filter repositoryFilter) error {
+	var stat *source.Stat
+	var err error
+	if lifetime {
+		stat, err = src.LifetimeStat(ctx, username)
+	} else {
+		stat, err = src.Stat(ctx, username, from, to)
+	}
 	if err != nil {
 		return err
 	}
+
+	if !filter.isDefault() {
+		repos, err := src.Repositories(ctx, username)
+		if err != nil {
+			return err
+		}
+		filtered := *stat
+		filtered.Stars = 0
+		filtered.Forks = 0
+		for _, repo := range repos {
+			if !filter.match(repo) {
+				continue
+			}
+			filtered.Stars += repo.Stars
+			if !repo.IsFork {
+				filtered.Forks += repo.Forks
+			}
+		}
+		stat = &filtered
+	}
+
 	data := render.StatsData{
 		Title: title,
-		Items: formatSourceStats(stat),
+		Items: formatSourceStats(stat, period),
 	}
 	return render.StatsRender(w, data)
 }
 
-func formatSourceStats(stat *source.Stat) []render.StatsItem {
+func formatSourceStats(stat *source.Stat, period string) []render.StatsItem {
 	return []render.StatsItem{
 		{
 			Id:    "stars",
@@ -67,27 +201,34 @@ func formatSourceStats(stat *source.Stat) []render.StatsItem {
 		},
 		{
 			Id:    "issues",
-			Key:   "Issues in a year",
+			Key:   "Issues " + period,
 			Value: formatInt(stat.Issues),
 		},
 		{
 			Id:    "commits",
-			Key:   "Commits in a year",
+			Key:   "Commits " + period,
 			Value: formatInt(stat.Commits),
 		},
 		{
 			Id:    "reviews",
-			Key:   "Reviews in a year",
+			Key:   "Reviews " + period,
 			Value: formatInt(stat.Reviews),
 		},
 		{
 			Id:    "prs",
-			Key:   "PRs in a year",
+			Key:   "PRs " + period,
 			Value: formatInt(stat.PullRequests),
 		},
 	}
 }
 
+// isYear reports whether the time window is a calendar year
+func isYear(from, to time.Time) bool {
+	return from.YearDay() == 1 &&
+		from.Hour() == 0 && from.Minute() == 0 && from.Second() == 0 &&
+		to.Equal(from.AddDate(1, 0, 0))
+}
+
 var fmtEn = message.NewPrinter(language.English)
 
 func formatInt(i int) string {
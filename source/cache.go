@@ -0,0 +1,287 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wzshiming/httpcache"
+)
+
+// The classes of the cached queries, each class has its own max age
+const (
+	CacheStats        = "stats"
+	CacheHistory      = "history"
+	CachePullRequests = "prs"
+	CacheIssues       = "issues"
+	CacheReviews      = "reviews"
+	CacheRepositories = "repos"
+	CacheOther        = "other"
+)
+
+// DefaultCacheMaxAge is the max age of each class, zero never expires.
+// The contributions of the past years never change, so they are kept.
+var DefaultCacheMaxAge = map[string]time.Duration{
+	CacheStats:        6 * time.Hour,
+	CacheHistory:      0,
+	CachePullRequests: time.Hour,
+	CacheIssues:       time.Hour,
+	CacheReviews:      time.Hour,
+	CacheRepositories: 6 * time.Hour,
+	CacheOther:        24 * time.Hour,
+}
+
+// CachePolicy is how the responses are cached in the directory
+type CachePolicy struct {
+	// Dir is the directory of the cache, the responses are only cached in memory if it's empty
+	Dir string
+	// MaxAge is the max age of each class, the missing classes use DefaultCacheMaxAge
+	MaxAge map[string]time.Duration
+	// Offline serves only the cached responses regardless of their age, and fails on the misses
+	Offline bool
+	// Fixtures is the directory of the recorded exchanges, which are served only and fail on the unknown requests,
+	// the cache directory is not used with the fixtures.
+	Fixtures string
+	// Record saves all the exchanges into the fixtures instead of serving them
+	Record bool
+}
+
+// ParseCacheMaxAge parses the max age of the classes like "stats=6h,prs=1h"
+func ParseCacheMaxAge(s string) (map[string]time.Duration, error) {
+	maxAge := map[string]time.Duration{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		class, age, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("cache max age %q: no duration", item)
+		}
+		if _, ok := DefaultCacheMaxAge[class]; !ok {
+			return nil, fmt.Errorf("cache max age %q: not support class %q", item, class)
+		}
+		d, err := time.ParseDuration(age)
+		if err != nil {
+			return nil, fmt.Errorf("cache max age %q: %w", item, err)
+		}
+		maxAge[class] = d
+	}
+	return maxAge, nil
+}
+
+func (p CachePolicy) maxAge(class string) time.Duration {
+	if d, ok := p.MaxAge[class]; ok {
+		return d
+	}
+	if d, ok := DefaultCacheMaxAge[class]; ok {
+		return d
+	}
+	return DefaultCacheMaxAge[CacheOther]
+}
+
+// options returns the options of the cache, the keys are prefixed by the class
+func (p CachePolicy) options(keyers ...httpcache.Keyer) []httpcache.Option {
+	opts := []httpcache.Option{
+		httpcache.WithKeyer(
+			httpcache.JointKeyer(append([]httpcache.Keyer{cacheClassKeyer()}, keyers...)...),
+		),
+		httpcache.WithDiscarder(successDiscarder()),
+	}
+	if p.Dir != "" && p.Fixtures == "" {
+		opts = append(opts, httpcache.WithStorer(&policyStorer{
+			policy: p,
+			storer: httpcache.DirectoryStorer(p.Dir),
+		}))
+	}
+	return opts
+}
+
+// transport returns the transport under the cache, which fails all the requests in offline mode
+func (p CachePolicy) transport(roundTripper http.RoundTripper) http.RoundTripper {
+	if p.Fixtures != "" {
+		return unchunkedRequest{graphQLErrorRequest{newFixtureRequest(roundTripper, p.Fixtures, p.Record)}}
+	}
+	if p.Offline {
+		return offlineRequest{}
+	}
+	return unchunkedRequest{graphQLErrorRequest{roundTripper}}
+}
+
+// unchunkedRequest drops the chunked transfer encoding of the responses, their bodies are decoded already,
+// as the cache writes the chunked responses encoded but reads them back without decoding.
+type unchunkedRequest struct {
+	roundTripper http.RoundTripper
+}
+
+func (u unchunkedRequest) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := u.roundTripper.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	resp.TransferEncoding = nil
+	return resp, nil
+}
+
+// headerGraphQLErrors marks the responses of the GraphQL queries which have errors
+const headerGraphQLErrors = "X-Profile-Stats-GraphQL-Errors"
+
+// successDiscarder discards all but the successful responses, as the history is kept forever,
+// the errors of the GraphQL queries are successful responses marked by graphQLErrorRequest.
+func successDiscarder() httpcache.Discarder {
+	return httpcache.DiscarderFunc(func(resp httpcache.Response) bool {
+		code := resp.StatusCode()
+		return code < 200 || code >= 300 || resp.Header().Get(headerGraphQLErrors) != ""
+	})
+}
+
+// graphQLErrorRequest marks the successful responses of the GraphQL queries which have errors
+type graphQLErrorRequest struct {
+	roundTripper http.RoundTripper
+}
+
+func (g graphQLErrorRequest) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := g.roundTripper.RoundTrip(r)
+	if err != nil || r.Method != http.MethodPost || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var out struct {
+		Errors []json.RawMessage `json:"errors"`
+	}
+	if json.Unmarshal(body, &out) == nil && len(out.Errors) != 0 && resp.Header != nil {
+		resp.Header.Set(headerGraphQLErrors, strconv.Itoa(len(out.Errors)))
+	}
+	return resp, nil
+}
+
+type cacheClassKey struct{}
+
+// withCacheClass sets the class of the queries in the context
+func withCacheClass(ctx context.Context, class string) context.Context {
+	return context.WithValue(ctx, cacheClassKey{}, class)
+}
+
+func cacheClassKeyer() httpcache.Keyer {
+	return httpcache.KeyerFunc(func(req *http.Request) string {
+		class, _ := req.Context().Value(cacheClassKey{}).(string)
+		if class == "" {
+			return CacheOther
+		}
+		return class
+	})
+}
+
+// policyStorer misses the stale entries of the directory
+type policyStorer struct {
+	policy CachePolicy
+	storer httpcache.Storer
+}
+
+func (s *policyStorer) Get(key string) (io.ReadCloser, bool) {
+	if !s.policy.Offline && s.stale(key, time.Now()) {
+		return nil, false
+	}
+	return s.storer.Get(key)
+}
+
+func (s *policyStorer) Put(key string) (io.WriteCloser, bool) {
+	return s.storer.Put(key)
+}
+
+func (s *policyStorer) Del(key string) bool {
+	return s.storer.Del(key)
+}
+
+func (s *policyStorer) stale(key string, now time.Time) bool {
+	maxAge := s.policy.maxAge(cacheClass(key))
+	if maxAge <= 0 {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(s.policy.Dir, key))
+	if err != nil {
+		return false
+	}
+	return now.Sub(info.ModTime()) > maxAge
+}
+
+// cacheClass returns the class of the key
+func cacheClass(key string) string {
+	class, _, _ := strings.Cut(path.Clean(filepath.ToSlash(key)), "/")
+	return class
+}
+
+// PruneCache deletes the stale entries and the temporary files left in the directory of the cache,
+// and the entries of the layout without classes which are never read. It returns the number of the deleted files.
+// The other files in the directory are not touched, as it may be shared.
+func PruneCache(policy CachePolicy) (int, error) {
+	s := &policyStorer{
+		policy: policy,
+	}
+	now := time.Now()
+	count := 0
+	prune := func(dir string, all bool) error {
+		root := filepath.Join(policy.Dir, dir)
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			return nil
+		}
+		return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			key, err := filepath.Rel(policy.Dir, p)
+			if err != nil {
+				return err
+			}
+			if !all && !strings.HasSuffix(key, ".tmp") && !s.stale(key, now) {
+				return nil
+			}
+			err = os.Remove(p)
+			if err != nil {
+				return err
+			}
+			count++
+			return nil
+		})
+	}
+
+	for class := range DefaultCacheMaxAge {
+		err := prune(class, false)
+		if err != nil {
+			return count, fmt.Errorf("prune cache %s: %w", policy.Dir, err)
+		}
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		err := prune(method, true)
+		if err != nil {
+			return count, fmt.Errorf("prune cache %s: %w", policy.Dir, err)
+		}
+	}
+	return count, nil
+}
+
+// offlineRequest fails the requests which are not cached
+type offlineRequest struct{}
+
+func (offlineRequest) RoundTrip(r *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("offline: %s %s is not cached", r.Method, r.URL)
+}
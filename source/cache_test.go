@@ -0,0 +1,188 @@
+package source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wzshiming/httpcache"
+)
+
+// age sets the modification time of all the files in the directory
+func age(t *testing.T, dir string, d time.Duration) {
+	old := time.Now().Add(-d)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		return os.Chtimes(p, old, old)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCachePolicy(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		count++
+		rw.Write([]byte(`[{"full_name":"foo/bar","stars_count":1}]`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	repositories := func(policy CachePolicy) error {
+		_, err := NewGitea(server.URL, "", policy, 0, 0).Repositories(ctx, "foo")
+		return err
+	}
+
+	err := repositories(CachePolicy{Offline: true, Dir: dir})
+	if err == nil || !strings.Contains(err.Error(), "offline") {
+		t.Fatalf("Repositories() offline error = %v", err)
+	}
+
+	for i := 0; i != 2; i++ {
+		err = repositories(CachePolicy{Dir: dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count != 1 {
+		t.Fatalf("requests = %d, want 1", count)
+	}
+
+	age(t, dir, 2*time.Hour)
+	err = repositories(CachePolicy{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("requests = %d, want the fresh entry cached", count)
+	}
+
+	err = repositories(CachePolicy{Dir: dir, MaxAge: map[string]time.Duration{CacheRepositories: time.Hour}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("requests = %d, want the stale entry fetched", count)
+	}
+
+	age(t, dir, 24*time.Hour)
+	err = repositories(CachePolicy{Dir: dir, Offline: true})
+	if err != nil {
+		t.Errorf("Repositories() offline with stale entry error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("requests = %d, want none offline", count)
+	}
+}
+
+func TestPruneCache(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]time.Duration{
+		"prs/GET/a":          2 * time.Hour,
+		"prs/GET/b":          0,
+		"history/POST/c":     24 * 365 * time.Hour,
+		"stats/POST/d.1.tmp": 0,
+		"POST/e":             0,
+		"git/foo/bar/f":      24 * 365 * time.Hour,
+	}
+	for name, d := range files {
+		p := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		err := os.WriteFile(p, nil, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		old := time.Now().Add(-d)
+		os.Chtimes(p, old, old)
+	}
+
+	count, err := PruneCache(CachePolicy{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("PruneCache() = %d, want 3", count)
+	}
+	for name, want := range map[string]bool{
+		"prs/GET/a":          false,
+		"prs/GET/b":          true,
+		"history/POST/c":     true,
+		"stats/POST/d.1.tmp": false,
+		"POST/e":             false,
+		"git/foo/bar/f":      true,
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if got := err == nil; got != want {
+			t.Errorf("%s exists = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestParseCacheMaxAge(t *testing.T) {
+	maxAge, err := ParseCacheMaxAge("stats=3h, prs=30m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxAge[CacheStats] != 3*time.Hour || maxAge[CachePullRequests] != 30*time.Minute {
+		t.Errorf("ParseCacheMaxAge() = %v", maxAge)
+	}
+	for _, s := range []string{"stats", "stats=abc", "unknown=1h"} {
+		_, err := ParseCacheMaxAge(s)
+		if err == nil {
+			t.Errorf("ParseCacheMaxAge(%q) want error", s)
+		}
+	}
+}
+
+func TestCacheDiscard(t *testing.T) {
+	responses := []func(rw http.ResponseWriter){
+		func(rw http.ResponseWriter) {
+			rw.WriteHeader(http.StatusForbidden)
+		},
+		func(rw http.ResponseWriter) {
+			rw.WriteHeader(http.StatusTooManyRequests)
+		},
+		func(rw http.ResponseWriter) {
+			rw.Write([]byte(`{"data":null,"errors":[{"message":"timeout"}]}`))
+		},
+		func(rw http.ResponseWriter) {
+			rw.Write([]byte(`{"data":{}}`))
+		},
+	}
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		responses[count](rw)
+		count++
+	}))
+	defer server.Close()
+
+	policy := CachePolicy{Dir: t.TempDir()}
+	cli := &http.Client{
+		Transport: httpcache.NewRoundTripper(policy.transport(http.DefaultTransport),
+			append([]httpcache.Option{
+				httpcache.WithFilterer(
+					httpcache.MethodFilterer(http.MethodPost),
+				),
+			}, policy.options(httpcache.MethodKeyer(), httpcache.PathKeyer())...)...,
+		),
+	}
+	for i := 0; i != len(responses)+1; i++ {
+		resp, err := cli.Post(server.URL, "application/json", strings.NewReader(`{}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if count != len(responses) {
+		t.Errorf("requests = %d, want only the success cached", count)
+	}
+}
@@ -12,6 +12,38 @@ const (
 	PullRequestStateMerged PullRequestState = ghv4.PullRequestStateMerged
 )
 
+type IssueState = ghv4.IssueState
+
+const (
+	IssueStateOpen   IssueState = ghv4.IssueStateOpen
+	IssueStateClosed IssueState = ghv4.IssueStateClosed
+)
+
+type PullRequestReviewState = ghv4.PullRequestReviewState
+
+const (
+	PullRequestReviewStateCommented        PullRequestReviewState = ghv4.PullRequestReviewStateCommented
+	PullRequestReviewStateApproved         PullRequestReviewState = ghv4.PullRequestReviewStateApproved
+	PullRequestReviewStateChangesRequested PullRequestReviewState = ghv4.PullRequestReviewStateChangesRequested
+	PullRequestReviewStateDismissed        PullRequestReviewState = ghv4.PullRequestReviewStateDismissed
+)
+
+type PullRequestReviewDecision = ghv4.PullRequestReviewDecision
+
+const (
+	PullRequestReviewDecisionChangesRequested PullRequestReviewDecision = ghv4.PullRequestReviewDecisionChangesRequested
+	PullRequestReviewDecisionApproved         PullRequestReviewDecision = ghv4.PullRequestReviewDecisionApproved
+	PullRequestReviewDecisionReviewRequired   PullRequestReviewDecision = ghv4.PullRequestReviewDecisionReviewRequired
+)
+
+type PullRequestMergeMethod = ghv4.PullRequestMergeMethod
+
+const (
+	PullRequestMergeMethodMerge  PullRequestMergeMethod = ghv4.PullRequestMergeMethodMerge
+	PullRequestMergeMethodSquash PullRequestMergeMethod = ghv4.PullRequestMergeMethodSquash
+	PullRequestMergeMethodRebase PullRequestMergeMethod = ghv4.PullRequestMergeMethodRebase
+)
+
 type IssueOrderField = ghv4.IssueOrderField
 
 const (
@@ -0,0 +1,168 @@
+package source
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"unicode/utf8"
+)
+
+// fixture is an exchange with the API, the authorization is not saved
+type fixture struct {
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Body     string      `json:"body,omitempty"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header,omitempty"`
+	Response string      `json:"response"`
+	// Encoding is base64 if the response isn't text
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// fixtureRequest records the exchanges into the directory, or replays them if there is no roundTripper
+type fixtureRequest struct {
+	dir          string
+	roundTripper http.RoundTripper
+}
+
+func newFixtureRequest(roundTripper http.RoundTripper, dir string, record bool) http.RoundTripper {
+	if !record {
+		roundTripper = nil
+	}
+	return &fixtureRequest{
+		dir:          dir,
+		roundTripper: roundTripper,
+	}
+}
+
+func (f *fixtureRequest) RoundTrip(r *http.Request) (*http.Response, error) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	name := fixtureName(r.Method, r.URL.String(), body)
+
+	if f.roundTripper == nil {
+		data, err := os.ReadFile(filepath.Join(f.dir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("replay: no fixture of %s %s", r.Method, r.URL)
+			}
+			return nil, fmt.Errorf("replay: %w", err)
+		}
+		var fx fixture
+		err = json.Unmarshal(data, &fx)
+		if err != nil {
+			return nil, fmt.Errorf("replay %s: %w", name, err)
+		}
+		resp, err := fx.response(r)
+		if err != nil {
+			return nil, fmt.Errorf("replay %s: %w", name, err)
+		}
+		return resp, nil
+	}
+
+	resp, err := f.roundTripper.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	// The limited and failed responses are retried, so they aren't the fixtures
+	if resp.StatusCode >= http.StatusInternalServerError ||
+		resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
+		return resp, nil
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	fx := fixture{
+		Method:   r.Method,
+		URL:      r.URL.String(),
+		Body:     string(body),
+		Status:   resp.StatusCode,
+		Header:   fixtureHeader(resp.Header),
+		Response: string(respBody),
+	}
+	if !utf8.Valid(respBody) {
+		fx.Response = base64.StdEncoding.EncodeToString(respBody)
+		fx.Encoding = "base64"
+	}
+	data, err := json.MarshalIndent(fx, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(f.dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("record: %w", err)
+	}
+	err = os.WriteFile(filepath.Join(f.dir, name), append(data, '\n'), 0644)
+	if err != nil {
+		return nil, fmt.Errorf("record: %w", err)
+	}
+	return resp, nil
+}
+
+func (fx *fixture) response(r *http.Request) (*http.Response, error) {
+	body := []byte(fx.Response)
+	if fx.Encoding == "base64" {
+		var err error
+		body, err = base64.StdEncoding.DecodeString(fx.Response)
+		if err != nil {
+			return nil, err
+		}
+	}
+	header := fx.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(fx.Status) + " " + http.StatusText(fx.Status),
+		StatusCode:    fx.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       r,
+	}, nil
+}
+
+// fixtureHeader keeps the headers which are read by the sources
+func fixtureHeader(header http.Header) http.Header {
+	kept := http.Header{}
+	for _, key := range []string{"Content-Type", "Link", "X-Next-Page", "X-Total-Count"} {
+		if v := header.Values(key); len(v) != 0 {
+			kept[key] = v
+		}
+	}
+	return kept
+}
+
+// fixtureName is the file name of the request
+func fixtureName(method, url string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{'\n'})
+	hash.Write([]byte(url))
+	hash.Write([]byte{'\n'})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))[:16] + ".json"
+}
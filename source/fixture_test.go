@@ -0,0 +1,142 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFixtureRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("X-Next-Page", "")
+		rw.Write([]byte(`[{"full_name":"foo/bar","stars_count":1}]`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	repos, err := NewGitea(server.URL, "", CachePolicy{Fixtures: dir, Record: true}, 0, 0).Repositories(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 1 {
+		t.Fatalf("Repositories() recorded = %d, want 1", len(repos))
+	}
+	server.Close()
+
+	repos, err = NewGitea(server.URL, "", CachePolicy{Fixtures: dir}, 0, 0).Repositories(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 1 || repos[0].Stars != 1 {
+		t.Errorf("Repositories() replayed = %v", repos)
+	}
+
+	_, err = NewGitea(server.URL, "", CachePolicy{Fixtures: dir}, 0, 0).Repositories(ctx, "bar")
+	if err == nil || !strings.Contains(err.Error(), "no fixture") {
+		t.Errorf("Repositories() unknown error = %v", err)
+	}
+}
+
+// networkRequest fails as the network is disabled
+type networkRequest struct{}
+
+func (networkRequest) RoundTrip(r *http.Request) (*http.Response, error) {
+	return nil, errors.New("network is disabled")
+}
+
+func TestFixtureRoundTrip(t *testing.T) {
+	image := []byte{0x89, 'P', 'N', 'G', 0xff, 0x00, 0xfe}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/graphql":
+			body, _ := io.ReadAll(r.Body)
+			rw.Header().Set("Content-Type", "application/json")
+			rw.Write([]byte(`{"data":{"query":` + strconv.Quote(string(body)) + `}}`))
+		case "/repos":
+			rw.Header().Set("Link", `<`+r.URL.String()+`?page=2>; rel="next"`)
+			rw.Header().Set("X-Total-Count", "2")
+			rw.Write([]byte("[{\"name\":\"caf\u00e9\"}]\n"))
+		case "/logo.png":
+			rw.Header().Set("Content-Type", "image/png")
+			rw.Write(image)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	type exchange struct {
+		status int
+		header http.Header
+		body   []byte
+	}
+	roundTrip := func(roundTripper http.RoundTripper, method, path, body string) (*exchange, error) {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		resp, err := roundTripper.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &exchange{resp.StatusCode, fixtureHeader(resp.Header), data}, nil
+	}
+	requests := []struct {
+		method, path, body string
+	}{
+		{http.MethodPost, "/api/graphql", `{"query":"{viewer{login}}"}`},
+		{http.MethodPost, "/api/graphql", `{"query":"{viewer{name}}"}`},
+		{http.MethodGet, "/repos", ""},
+		{http.MethodGet, "/logo.png", ""},
+		{http.MethodGet, "/missing", ""},
+	}
+
+	// RECORD=true FIXTURES_DIR=<dir>
+	dir := t.TempDir()
+	recorder := CachePolicy{Fixtures: dir, Record: true}.transport(http.DefaultTransport)
+	recorded := []*exchange{}
+	for _, r := range requests {
+		e, err := roundTrip(recorder, r.method, r.path, r.body)
+		if err != nil {
+			t.Fatalf("record %s %s: %v", r.method, r.path, err)
+		}
+		recorded = append(recorded, e)
+	}
+	if !bytes.Equal(recorded[3].body, image) {
+		t.Fatalf("recorded %q, want %q", recorded[3].body, image)
+	}
+	server.Close()
+
+	// FIXTURES_DIR=<dir>
+	replayer := CachePolicy{Fixtures: dir}.transport(networkRequest{})
+	for i, r := range requests {
+		e, err := roundTrip(replayer, r.method, r.path, r.body)
+		if err != nil {
+			t.Fatalf("replay %s %s: %v", r.method, r.path, err)
+		}
+		if e.status != recorded[i].status || !bytes.Equal(e.body, recorded[i].body) {
+			t.Errorf("replay %s %s = %d %q, want %d %q", r.method, r.path, e.status, e.body, recorded[i].status, recorded[i].body)
+		}
+		if fmt.Sprint(e.header) != fmt.Sprint(recorded[i].header) {
+			t.Errorf("replay %s %s header = %v, want %v", r.method, r.path, e.header, recorded[i].header)
+		}
+	}
+
+	_, err := roundTrip(replayer, http.MethodPost, "/api/graphql", `{"query":"{viewer{id}}"}`)
+	if err == nil || !strings.Contains(err.Error(), "no fixture") {
+		t.Errorf("replay unknown error = %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+var (
+	_ Source       = (*Git)(nil)
+	_ CommitLister = (*Git)(nil)
+)
+
+type Commit struct {
+	Hash        string
+	AuthorName  string
+	AuthorEmail string
+	When        time.Time
+}
+
+// CommitLister is implemented by the sources which can list the commit history
+type CommitLister interface {
+	// Commits lists the commits authored by username since the time, username matches the author email or name
+	Commits(ctx context.Context, username string, since time.Time) ([]*Commit, error)
+}
+
+// Git is the source of a local clone, it only lists the commits reachable from HEAD
+type Git struct {
+	path string
+
+	mut  sync.Mutex
+	repo *gogit.Repository
+}
+
+func NewGit(path string) *Git {
+	return &Git{
+		path: path,
+	}
+}
+
+func (s *Git) Stat(ctx context.Context, username string, from, to time.Time) (*Stat, error) {
+	return nil, fmt.Errorf("git stat: %w", ErrNotSupported)
+}
+
+func (s *Git) LifetimeStat(ctx context.Context, username string) (*Stat, error) {
+	return nil, fmt.Errorf("git lifetime stat: %w", ErrNotSupported)
+}
+
+func (s *Git) Repositories(ctx context.Context, username string) ([]*Repository, error) {
+	return nil, fmt.Errorf("git repositories: %w", ErrNotSupported)
+}
+
+func (s *Git) OrgStat(ctx context.Context, username string, org string, from, to time.Time) (*OrgStat, error) {
+	return nil, fmt.Errorf("git organization stat: %w", ErrNotSupported)
+}
+
+func (s *Git) PullRequests(ctx context.Context, username string, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...PullRequestCallback) ([]*PullRequest, error) {
+	return nil, fmt.Errorf("git pull requests: %w", ErrNotSupported)
+}
+
+func (s *Git) ScopedPullRequests(ctx context.Context, scope Scope, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...PullRequestCallback) ([]*PullRequest, error) {
+	return nil, fmt.Errorf("git pull requests of %s: %w", scope, ErrNotSupported)
+}
+
+func (s *Git) Issues(ctx context.Context, username string, states []IssueState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...IssueCallback) ([]*Issue, error) {
+	return nil, fmt.Errorf("git issues: %w", ErrNotSupported)
+}
+
+func (s *Git) Reviews(ctx context.Context, username string, from, to time.Time, size int, cbs ...ReviewCallback) ([]*Review, error) {
+	return nil, fmt.Errorf("git reviews: %w", ErrNotSupported)
+}
+
+func (s *Git) Commits(ctx context.Context, username string, since time.Time) ([]*Commit, error) {
+	repo, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &gogit.LogOptions{
+		Order: gogit.LogOrderCommitterTime,
+	}
+	if !since.IsZero() {
+		opts.Since = &since
+	}
+	iter, err := repo.Log(opts)
+	if err != nil {
+		return nil, fmt.Errorf("git log %s: %w", s.path, err)
+	}
+	defer iter.Close()
+
+	commits := []*Commit{}
+	err = iter.ForEach(func(c *object.Commit) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !strings.EqualFold(c.Author.Email, username) && c.Author.Name != username {
+			return nil
+		}
+		if c.Author.When.Before(since) {
+			return nil
+		}
+		commits = append(commits, &Commit{
+			Hash:        c.Hash.String(),
+			AuthorName:  c.Author.Name,
+			AuthorEmail: c.Author.Email,
+			When:        c.Author.When,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("git log %s: %w", s.path, err)
+	}
+	return commits, nil
+}
+
+func (s *Git) open() (*gogit.Repository, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.repo != nil {
+		return s.repo, nil
+	}
+	repo, err := gogit.PlainOpenWithOptions(s.path, &gogit.PlainOpenOptions{
+		DetectDotGit: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("git open %s: %w", s.path, err)
+	}
+	s.repo = repo
+	return repo, nil
+}
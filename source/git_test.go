@@ -0,0 +1,66 @@
+package source
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestGitCommits(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := gogit.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	work, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	authors := []object.Signature{
+		{Name: "Foo", Email: "foo@example.com", When: now.AddDate(0, -3, 0)},
+		{Name: "Foo", Email: "foo@example.com", When: now.AddDate(0, -1, 0)},
+		{Name: "Bar", Email: "bar@example.com", When: now.AddDate(0, -1, 0)},
+		{Name: "Foo", Email: "FOO@example.com", When: now},
+	}
+	for i, author := range authors {
+		err = os.WriteFile(filepath.Join(dir, "file"), []byte{byte(i)}, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = work.Add("file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = work.Commit("commit", &gogit.CommitOptions{
+			Author:    &author,
+			Committer: &author,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src := NewGit(dir)
+	commits, err := src.Commits(context.Background(), "foo@example.com", now.AddDate(0, -2, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("Commits() got %d, want 2", len(commits))
+	}
+
+	commits, err = src.Commits(context.Background(), "Bar", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 || commits[0].AuthorEmail != "bar@example.com" {
+		t.Errorf("Commits() = %+v", commits)
+	}
+}
@@ -0,0 +1,395 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const giteaPageSize = 50
+
+var _ Source = (*Gitea)(nil)
+
+// Gitea is the source of Gitea and Forgejo, it only lists pull requests, issues and repositories
+type Gitea struct {
+	cli *restClient
+}
+
+func NewGitea(baseURL string, token string, cache CachePolicy, interval time.Duration, retry int) *Gitea {
+	return &Gitea{
+		cli: newRESTClient(strings.TrimSuffix(baseURL, "/")+"/api/v1", token, cache, interval, retry),
+	}
+}
+
+type giteaActivity struct {
+	OpType string `json:"op_type"`
+	Repo   struct {
+		FullName string `json:"full_name"`
+	} `json:"repo"`
+	Content string `json:"content"`
+}
+
+type giteaRepository struct {
+	FullName string `json:"full_name"`
+	Stars    int    `json:"stars_count"`
+	Forks    int    `json:"forks_count"`
+	Fork     bool   `json:"fork"`
+	Archived bool   `json:"archived"`
+}
+
+type giteaIssue struct {
+	Number   int    `json:"number"`
+	Title    string `json:"title"`
+	State    string `json:"state"`
+	HTMLURL  string `json:"html_url"`
+	Comments int    `json:"comments"`
+	User     struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ClosedAt  time.Time `json:"closed_at"`
+}
+
+type giteaPullRequest struct {
+	Number       int    `json:"number"`
+	Title        string `json:"title"`
+	State        string `json:"state"`
+	Merged       bool   `json:"merged"`
+	HTMLURL      string `json:"html_url"`
+	Additions    int    `json:"additions"`
+	Deletions    int    `json:"deletions"`
+	ChangedFiles int    `json:"changed_files"`
+	User         struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	Draft    bool `json:"draft"`
+	Comments int  `json:"comments"`
+	MergedBy struct {
+		Login string `json:"login"`
+	} `json:"merged_by"`
+	RequestedReviewers []struct {
+		Login string `json:"login"`
+	} `json:"requested_reviewers"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	MergedAt  time.Time `json:"merged_at"`
+	ClosedAt  time.Time `json:"closed_at"`
+}
+
+func (s *Gitea) Stat(ctx context.Context, username string, from, to time.Time) (*Stat, error) {
+	return nil, fmt.Errorf("gitea stat: %w", ErrNotSupported)
+}
+
+func (s *Gitea) LifetimeStat(ctx context.Context, username string) (*Stat, error) {
+	return nil, fmt.Errorf("gitea lifetime stat: %w", ErrNotSupported)
+}
+
+func (s *Gitea) Repositories(ctx context.Context, username string) ([]*Repository, error) {
+	ctx = withCacheClass(ctx, CacheRepositories)
+	query := url.Values{
+		"limit": []string{strconv.Itoa(giteaPageSize)},
+	}
+	path := fmt.Sprintf("/users/%s/repos", url.PathEscape(username))
+	repos := []*Repository{}
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		var rs []giteaRepository
+		_, err := s.cli.get(ctx, path, query, &rs)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rs {
+			repos = append(repos, &Repository{
+				Name:       r.FullName,
+				Stars:      r.Stars,
+				Forks:      r.Forks,
+				IsFork:     r.Fork,
+				IsArchived: r.Archived,
+			})
+		}
+		if len(rs) < giteaPageSize {
+			return repos, nil
+		}
+	}
+}
+
+func (s *Gitea) OrgStat(ctx context.Context, username string, org string, from, to time.Time) (*OrgStat, error) {
+	return nil, fmt.Errorf("gitea organization stat: %w", ErrNotSupported)
+}
+
+// PullRequests lists the pull requests created by the user from the activity feeds,
+// so they are always ordered by creation time, newest first.
+func (s *Gitea) PullRequests(ctx context.Context, username string, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...PullRequestCallback) ([]*PullRequest, error) {
+	ctx = withCacheClass(ctx, CachePullRequests)
+	if orderField != IssueOrderFieldCreatedAt || orderDirection != OrderDirectionDesc {
+		return nil, fmt.Errorf("gitea order by %s %s: %w", orderField, orderDirection, ErrNotSupported)
+	}
+	if len(states) == 0 {
+		states = []PullRequestState{PullRequestStateOpen}
+	}
+
+	prs := []*PullRequest{}
+	if size == 0 {
+		return prs, nil
+	}
+	err := s.eachFeed(ctx, username, "create_pull_request", func(repo string, index int) (bool, error) {
+		pr, err := s.pullRequest(ctx, repo, index, states)
+		if err != nil {
+			return false, err
+		}
+		if pr == nil {
+			return true, nil
+		}
+		for _, cb := range cbs {
+			if !cb(pr) {
+				return false, nil
+			}
+		}
+		prs = append(prs, pr)
+		return size < 0 || len(prs) < size, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prs, nil
+}
+
+// ScopedPullRequests is not supported, as the pull requests are only listed from the activity feeds of the users
+func (s *Gitea) ScopedPullRequests(ctx context.Context, scope Scope, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...PullRequestCallback) ([]*PullRequest, error) {
+	return nil, fmt.Errorf("gitea pull requests of %s: %w", scope, ErrNotSupported)
+}
+
+// Issues lists the issues created by the user from the activity feeds,
+// so they are always ordered by creation time, newest first.
+func (s *Gitea) Issues(ctx context.Context, username string, states []IssueState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...IssueCallback) ([]*Issue, error) {
+	ctx = withCacheClass(ctx, CacheIssues)
+	if orderField != IssueOrderFieldCreatedAt || orderDirection != OrderDirectionDesc {
+		return nil, fmt.Errorf("gitea order by %s %s: %w", orderField, orderDirection, ErrNotSupported)
+	}
+	if len(states) == 0 {
+		states = []IssueState{IssueStateOpen}
+	}
+
+	issues := []*Issue{}
+	if size == 0 {
+		return issues, nil
+	}
+	err := s.eachFeed(ctx, username, "create_issue", func(repo string, index int) (bool, error) {
+		issue, err := s.issue(ctx, repo, index, states)
+		if err != nil {
+			return false, err
+		}
+		if issue == nil {
+			return true, nil
+		}
+		for _, cb := range cbs {
+			if !cb(issue) {
+				return false, nil
+			}
+		}
+		issues = append(issues, issue)
+		return size < 0 || len(issues) < size, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// eachFeed calls next with the repository and index of each activity of the operation type performed by the user,
+// the activities without the index are skipped.
+func (s *Gitea) eachFeed(ctx context.Context, username string, opType string, next func(repo string, index int) (bool, error)) error {
+	query := url.Values{
+		"only-performed-by": []string{"true"},
+		"limit":             []string{strconv.Itoa(giteaPageSize)},
+	}
+	path := fmt.Sprintf("/users/%s/activities/feeds", url.PathEscape(username))
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		var activities []giteaActivity
+		_, err := s.cli.get(ctx, path, query, &activities)
+		if err != nil {
+			return err
+		}
+		for _, activity := range activities {
+			if activity.OpType != opType {
+				continue
+			}
+			index, err := strconv.Atoi(strings.SplitN(activity.Content, "|", 2)[0])
+			if err != nil || index <= 0 {
+				continue
+			}
+			ok, err := next(activity.Repo.FullName, index)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+		}
+		if len(activities) < giteaPageSize {
+			return nil
+		}
+	}
+}
+
+// pullRequest gets the pull request of the repository, it returns nil if the state is not listed.
+// The details are fetched for each pull request unless they're deferred by the context.
+func (s *Gitea) pullRequest(ctx context.Context, repo string, index int, states []PullRequestState) (*PullRequest, error) {
+	var r giteaPullRequest
+	_, err := s.cli.get(ctx, fmt.Sprintf("/repos/%s/pulls/%d", repo, index), nil, &r)
+	if err != nil {
+		return nil, err
+	}
+
+	state := PullRequestStateOpen
+	switch {
+	case r.Merged:
+		state = PullRequestStateMerged
+	case r.State == "closed":
+		state = PullRequestStateClosed
+	}
+	listed := false
+	for _, s := range states {
+		if s == state {
+			listed = true
+			break
+		}
+	}
+	if !listed {
+		return nil, nil
+	}
+
+	u, err := url.Parse(r.HTMLURL)
+	if err != nil {
+		return nil, err
+	}
+	p := PullRequest{
+		Username:     r.User.Login,
+		Repository:   repo,
+		Number:       r.Number,
+		Title:        r.Title,
+		URL:          u,
+		BaseRef:      r.Base.Ref,
+		State:        string(state),
+		Additions:    r.Additions,
+		Deletions:    r.Deletions,
+		ChangedFiles: r.ChangedFiles,
+		ChangeSize:   changeSize(r.Additions + r.Deletions),
+		CreatedAt:    r.CreatedAt,
+		ClosedAt:     r.ClosedAt,
+		MergedAt:     r.MergedAt,
+		UpdatedAt:    r.UpdatedAt,
+		SortTime:     r.UpdatedAt,
+		IsDraft:      r.Draft,
+		Comments:     r.Comments,
+		MergedBy:     r.MergedBy.Login,
+	}
+	if len(r.Labels) != 0 {
+		labels := make([]string, 0, len(r.Labels))
+		for _, label := range r.Labels {
+			labels = append(labels, label.Name)
+		}
+		p.Labels = labels
+	}
+	for _, reviewer := range r.RequestedReviewers {
+		p.RequestedReviewers = append(p.RequestedReviewers, reviewer.Login)
+	}
+
+	if !pullRequestDetailsDeferred(ctx) {
+		err = s.pullRequestDetails(ctx, &p)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	setSortTime(&p, states)
+	return &p, nil
+}
+
+// pullRequestDetails counts the commits of the pull request, which aren't in the pull request
+func (s *Gitea) pullRequestDetails(ctx context.Context, pr *PullRequest) error {
+	var commits []struct{}
+	header, err := s.cli.get(ctx, fmt.Sprintf("/repos/%s/pulls/%d/commits", pr.Repository, pr.Number), url.Values{
+		"limit":        []string{"1"},
+		"stat":         []string{"false"},
+		"verification": []string{"false"},
+		"files":        []string{"false"},
+	}, &commits)
+	if err != nil {
+		return err
+	}
+	pr.Commits = len(commits)
+	if total, err := strconv.Atoi(header.Get("X-Total-Count")); err == nil {
+		pr.Commits = total
+	}
+	return nil
+}
+
+// issue gets the issue of the repository, it returns nil if the state is not listed
+func (s *Gitea) issue(ctx context.Context, repo string, index int, states []IssueState) (*Issue, error) {
+	var r giteaIssue
+	_, err := s.cli.get(ctx, fmt.Sprintf("/repos/%s/issues/%d", repo, index), nil, &r)
+	if err != nil {
+		return nil, err
+	}
+
+	state := IssueStateOpen
+	if r.State == "closed" {
+		state = IssueStateClosed
+	}
+	listed := false
+	for _, s := range states {
+		if s == state {
+			listed = true
+			break
+		}
+	}
+	if !listed {
+		return nil, nil
+	}
+
+	u, err := url.Parse(r.HTMLURL)
+	if err != nil {
+		return nil, err
+	}
+	i := Issue{
+		Username:   r.User.Login,
+		Repository: repo,
+		Number:     r.Number,
+		Title:      r.Title,
+		URL:        u,
+		State:      string(state),
+		Comments:   r.Comments,
+		CreatedAt:  r.CreatedAt,
+		ClosedAt:   r.ClosedAt,
+		UpdatedAt:  r.UpdatedAt,
+		SortTime:   r.UpdatedAt,
+	}
+	if len(r.Labels) != 0 {
+		labels := make([]string, 0, len(r.Labels))
+		for _, label := range r.Labels {
+			labels = append(labels, label.Name)
+		}
+		i.Labels = labels
+	}
+	setIssueSortTime(&i, states)
+	return &i, nil
+}
+
+func (s *Gitea) Reviews(ctx context.Context, username string, from, to time.Time, size int, cbs ...ReviewCallback) ([]*Review, error) {
+	return nil, fmt.Errorf("gitea reviews: %w", ErrNotSupported)
+}
@@ -0,0 +1,136 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGiteaPullRequests(t *testing.T) {
+	routes := map[string]string{
+		"/api/v1/users/foo/activities/feeds": `[
+			{"op_type":"create_pull_request","repo":{"full_name":"org/repo"},"content":"3|Fix"},
+			{"op_type":"commit_repo","repo":{"full_name":"org/repo"},"content":"{}"},
+			{"op_type":"create_pull_request","repo":{"full_name":"org/repo"},"content":"../../admin|Not an index"},
+			{"op_type":"create_pull_request","repo":{"full_name":"org/repo"},"content":"2|Closed"}
+		]`,
+		"/api/v1/repos/org/repo/pulls/3": `{
+			"number":3,"title":"Fix","state":"closed","merged":true,
+			"html_url":"https://forgejo.example.com/org/repo/pulls/3",
+			"additions":12,"deletions":3,"changed_files":2,
+			"user":{"login":"foo"},"base":{"ref":"main"},"labels":[{"name":"bug"}],
+			"created_at":"2024-01-02T03:04:05Z","updated_at":"2024-01-04T03:04:05Z",
+			"merged_at":"2024-01-03T03:04:05Z","closed_at":"2024-01-03T03:04:05Z"
+		}`,
+		"/api/v1/repos/org/repo/pulls/3/commits": `[{}]`,
+		"/api/v1/repos/org/repo/pulls/2": `{
+			"number":2,"title":"Closed","state":"closed","merged":false,
+			"html_url":"https://forgejo.example.com/org/repo/pulls/2",
+			"user":{"login":"foo"},"base":{"ref":"main"},
+			"created_at":"2024-01-01T03:04:05Z","updated_at":"2024-01-01T03:04:05Z",
+			"closed_at":"2024-01-01T03:04:05Z"
+		}`,
+	}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		requests++
+		body, ok := routes[r.URL.Path]
+		if !ok {
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(rw, r)
+			return
+		}
+		if r.URL.Path == "/api/v1/repos/org/repo/pulls/3/commits" {
+			rw.Header().Set("X-Total-Count", "4")
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	src := NewGitea(server.URL, "", CachePolicy{}, 0, 0)
+	prs, err := src.PullRequests(context.Background(), "foo",
+		[]PullRequestState{PullRequestStateMerged},
+		IssueOrderFieldCreatedAt, OrderDirectionDesc, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 1 {
+		t.Fatalf("PullRequests() got %d, want 1", len(prs))
+	}
+	pr := prs[0]
+	if pr.Repository != "org/repo" || pr.Number != 3 || pr.BaseRef != "main" || pr.Username != "foo" {
+		t.Errorf("PullRequests() = %+v", pr)
+	}
+	if pr.State != string(PullRequestStateMerged) || !pr.SortTime.Equal(pr.MergedAt) {
+		t.Errorf("PullRequests() state = %s, sort time = %s", pr.State, pr.SortTime)
+	}
+	if pr.Additions != 12 || pr.Deletions != 3 || pr.ChangedFiles != 2 || pr.Commits != 4 {
+		t.Errorf("PullRequests() change = +%d -%d files %d commits %d", pr.Additions, pr.Deletions, pr.ChangedFiles, pr.Commits)
+	}
+	if len(pr.Labels) != 1 || pr.Labels[0] != "bug" {
+		t.Errorf("PullRequests() labels = %v", pr.Labels)
+	}
+
+	prs, err = src.PullRequests(context.Background(), "foo", nil, IssueOrderFieldCreatedAt, OrderDirectionDesc, 0)
+	if err != nil || len(prs) != 0 {
+		t.Errorf("PullRequests() with size 0 = %d, %v", len(prs), err)
+	}
+
+	// The commits of the pull requests not listed are not fetched, as there is no route of them
+	requests = 0
+	memo := NewMemo(NewGitea(server.URL, "", CachePolicy{}, 0, 0))
+	prs, err = memo.PullRequests(context.Background(), "foo",
+		[]PullRequestState{PullRequestStateMerged},
+		IssueOrderFieldCreatedAt, OrderDirectionDesc, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 1 || prs[0].Commits != 4 {
+		t.Errorf("PullRequests() memoized = %+v", prs)
+	}
+	if requests != 4 {
+		t.Errorf("requests = %d, want the feeds, the pull requests and the commits of #3", requests)
+	}
+}
+
+func TestGiteaRepositories(t *testing.T) {
+	var pages []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/users/foo/repos" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+		n, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if page != "1" {
+			n = 1
+		}
+		repos := make([]string, 0, n)
+		for i := 0; i != n; i++ {
+			repos = append(repos, fmt.Sprintf(`{"full_name":"foo/p%sr%d","stars_count":1}`, page, i))
+		}
+		rw.Write([]byte("[" + strings.Join(repos, ",") + "]"))
+	}))
+	defer server.Close()
+
+	// The pages are served from the cache offline
+	dir := t.TempDir()
+	for _, cache := range []CachePolicy{{Dir: dir}, {Dir: dir, Offline: true}} {
+		repos, err := NewGitea(server.URL, "", cache, 0, 0).Repositories(context.Background(), "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(repos) != giteaPageSize+1 || repos[0].Name != "foo/p1r0" || repos[giteaPageSize].Name != "foo/p2r0" {
+			t.Errorf("Repositories() = %d repositories", len(repos))
+		}
+	}
+	if strings.Join(pages, ",") != "1,2" {
+		t.Errorf("pages = %v, want 1,2", pages)
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"iter"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	ghv3 "github.com/google/go-github/v66/github"
@@ -15,33 +19,15 @@ import (
 	"golang.org/x/oauth2"
 )
 
-const MaxPageSize = 100
-
-type Stat struct {
-	Name          string
-	Stars         int
-	Forks         int
-	Issues        int
-	Commits       int
-	Reviews       int
-	PullRequests  int
-	ContributedTo int
-}
-
-type OrgStat struct {
-	Name         string
-	LogoURL      string
-	Issues       int
-	Commits      int
-	Reviews      int
-	PullRequests int
-}
-
+// intervalRequest paces the requests by a token bucket which is refilled one token per interval,
+// it's safe to be used by multiple goroutines.
 type intervalRequest struct {
 	retry         int
 	interval      time.Duration
-	last          time.Time
 	roundTripperr http.RoundTripper
+
+	mut  sync.Mutex
+	next time.Time
 }
 
 func newIntervalRequest(roundTripperr http.RoundTripper, interval time.Duration, retry int) http.RoundTripper {
@@ -55,22 +41,35 @@ func newIntervalRequest(roundTripperr http.RoundTripper, interval time.Duration,
 	}
 }
 
-func (l *intervalRequest) RoundTrip(r *http.Request) (*http.Response, error) {
-	defer func() {
-		l.last = time.Now()
-	}()
+// wait takes a token from the bucket, it waits until the token is refilled if the bucket is empty
+func (l *intervalRequest) wait(ctx context.Context) error {
+	l.mut.Lock()
 	now := time.Now()
-	sub := now.Sub(l.last)
-	if s := l.interval - sub; s > 0 {
-		time.Sleep(s)
+	if l.next.Before(now) {
+		l.next = now
 	}
-	tmpBody := bytes.NewBuffer(make([]byte, 0, r.ContentLength))
-	r.Body = struct {
-		io.Reader
-		io.Closer
-	}{
-		Reader: io.TeeReader(r.Body, tmpBody),
-		Closer: r.Body,
+	d := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mut.Unlock()
+	return sleepContext(ctx, d)
+}
+
+func (l *intervalRequest) RoundTrip(r *http.Request) (*http.Response, error) {
+	err := l.wait(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	var tmpBody *bytes.Buffer
+	if r.Body != nil {
+		tmpBody = bytes.NewBuffer(make([]byte, 0, r.ContentLength))
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{
+			Reader: io.TeeReader(r.Body, tmpBody),
+			Closer: r.Body,
+		}
 	}
 
 	resp, err := l.roundTripperr.RoundTrip(r)
@@ -81,8 +80,13 @@ func (l *intervalRequest) RoundTrip(r *http.Request) (*http.Response, error) {
 	for i := 0; i < l.retry && resp.StatusCode >= http.StatusInternalServerError; i++ {
 		io.Copy(io.Discard, resp.Body)
 		resp.Body.Close()
-		time.Sleep(l.interval)
-		r.Body = io.NopCloser(bytes.NewReader(tmpBody.Bytes()))
+		err = l.wait(r.Context())
+		if err != nil {
+			return nil, err
+		}
+		if tmpBody != nil {
+			r.Body = io.NopCloser(bytes.NewReader(tmpBody.Bytes()))
+		}
 		resp, err = l.roundTripperr.RoundTrip(r)
 		if err != nil {
 			return nil, err
@@ -91,97 +95,250 @@ func (l *intervalRequest) RoundTrip(r *http.Request) (*http.Response, error) {
 	return resp, nil
 }
 
-func NewSource(token string, cache string, interval time.Duration, retry int) *Source {
+// GitHubURL is the web URL of github.com, the other base URLs are of GitHub Enterprise Server
+const GitHubURL = "https://github.com"
+
+// NewGitHub returns the source of GitHub with the token, an empty baseURL selects github.com,
+// the APIs of GitHub Enterprise Server are at baseURL/api/v3 and baseURL/api/graphql.
+func NewGitHub(baseURL string, token string, cache CachePolicy, interval time.Duration, retry int) (*GitHub, error) {
 	src := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
 	)
+	return NewGitHubWithTokenSource(baseURL, src, cache, interval, retry)
+}
+
+// NewGitHubWithTokenSource is NewGitHub with the tokens of the source, e.g. the tokens of GitHub App installation
+func NewGitHubWithTokenSource(baseURL string, src oauth2.TokenSource, cache CachePolicy, interval time.Duration, retry int) (*GitHub, error) {
 	ctx := context.Background()
 	transport := oauth2.NewClient(ctx, src).Transport
+	return newGitHub(baseURL, transport, nil, cache, interval, retry)
+}
+
+// NewGitHubWithTokens is NewGitHub with the pool of the tokens, the next token is used when the quota of the current one is exhausted.
+// The responses are cached for each token, so the private data fetched with a token is never served to the others.
+func NewGitHubWithTokens(baseURL string, tokens []string, cache CachePolicy, interval time.Duration, retry int) (*GitHub, error) {
+	switch len(tokens) {
+	case 0:
+		return nil, fmt.Errorf("no token")
+	case 1:
+		return NewGitHub(baseURL, tokens[0], cache, interval, retry)
+	}
+	pool := newTokenPool(tokens)
+	return newGitHub(baseURL, pool.auth(http.DefaultTransport), pool, cache, interval, retry)
+}
+
+func newGitHub(baseURL string, transport http.RoundTripper, pool *tokenPool, cache CachePolicy, interval time.Duration, retry int) (*GitHub, error) {
+	transport = newIntervalRequest(newRateLimitRequest(cache.transport(transport)), interval, retry)
+
+	var keyers []httpcache.Keyer
+	pin := func(rt http.RoundTripper) http.RoundTripper {
+		return rt
+	}
+	if pool != nil {
+		keyers = append(keyers, pool.keyer())
+		pin = pool.pin
+	}
+	cliv3 := ghv3.NewClient(&http.Client{
+		Transport: pin(httpcache.NewRoundTripper(transport,
+			append([]httpcache.Option{
+				httpcache.WithFilterer(
+					httpcache.MethodFilterer(http.MethodGet),
+				),
+			}, cache.options(append(keyers,
+				httpcache.MethodKeyer(),
+				httpcache.PathKeyer(),
+			)...)...)...,
+		)),
+	})
+	cliv4 := &http.Client{
+		Transport: pin(httpcache.NewRoundTripper(transport,
+			append([]httpcache.Option{
+				httpcache.WithFilterer(
+					httpcache.MethodFilterer(http.MethodPost),
+				),
+			}, cache.options(append(keyers,
+				httpcache.MethodKeyer(),
+				httpcache.PathKeyer(),
+				httpcache.BodyKeyer(),
+			)...)...)...,
+		)),
+	}
+
+	baseURL, err := gitHubEnterpriseURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	// The assets are on the other hosts, so they're fetched without the token
+	assets := newRESTClient("", "", cache, interval, retry)
+	if baseURL == "" {
+		return &GitHub{
+			cliv3:  cliv3,
+			cliv4:  ghv4.NewClient(cliv4),
+			assets: assets,
+		}, nil
+	}
+	cliv3, err = cliv3.WithEnterpriseURLs(baseURL+"/api/v3/", baseURL+"/api/uploads/")
+	if err != nil {
+		return nil, fmt.Errorf("base url %q: %w", baseURL, err)
+	}
+	return &GitHub{
+		cliv3:  cliv3,
+		cliv4:  ghv4.NewEnterpriseClient(baseURL+"/api/graphql", cliv4),
+		assets: assets,
+	}, nil
+}
+
+// gitHubEnterpriseURL returns the web URL of GitHub Enterprise Server, or empty for github.com
+func gitHubEnterpriseURL(baseURL string) (string, error) {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if baseURL == "" || baseURL == GitHubURL {
+		return "", nil
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("base url %q: %w", baseURL, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("base url %q: no scheme or host", baseURL)
+	}
+	return strings.TrimSuffix(baseURL, "/api/v3"), nil
+}
+
+type GitHub struct {
+	cliv3  *ghv3.Client
+	cliv4  *ghv4.Client
+	assets *restClient
+
+	mut   sync.Mutex
+	years map[string]*Stat
+}
+
+// Asset fetches the asset like the avatar of the organization, it's cached like the queries
+func (s *GitHub) Asset(ctx context.Context, uri string) ([]byte, string, error) {
+	return s.assets.asset(withCacheClass(ctx, CacheOther), uri)
+}
+
+func (s *GitHub) Stat(ctx context.Context, username string, from, to time.Time) (*Stat, error) {
+	stat, _, err := s.stat(ctx, username, from, to)
+	return stat, err
+}
+
+// LifetimeStat sums the contributions of each contribution year,
+// the past years never change so they are only fetched once.
+func (s *GitHub) LifetimeStat(ctx context.Context, username string) (*Stat, error) {
+	now := time.Now().UTC()
+	begin := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	stat, years, err := s.stat(ctx, username, begin, now)
+	if err != nil {
+		return nil, err
+	}
+	for _, year := range years {
+		if year >= now.Year() {
+			continue
+		}
+		ys, err := s.yearStat(ctx, username, year)
+		if err != nil {
+			return nil, err
+		}
+		stat.Commits += ys.Commits
+		stat.Reviews += ys.Reviews
+		stat.PullRequests += ys.PullRequests
+		stat.Issues += ys.Issues
+	}
+	return stat, nil
+}
+
+// yearStat counts the contributions of a past calendar year, the query is the same each time so it can be cached
+func (s *GitHub) yearStat(ctx context.Context, username string, year int) (*Stat, error) {
+	ctx = withCacheClass(ctx, CacheHistory)
+	key := username + "/" + strconv.Itoa(year)
+	s.mut.Lock()
+	stat, ok := s.years[key]
+	s.mut.Unlock()
+	if ok {
+		return stat, nil
+	}
 
-	opts := []httpcache.Option{}
-	if cache != "" {
-		opts = append(opts, httpcache.WithStorer(httpcache.DirectoryStorer(cache)))
-	}
-
-	transport = newIntervalRequest(transport, interval, retry)
-	return &Source{
-		cliv3: ghv3.NewClient(&http.Client{
-			Transport: httpcache.NewRoundTripper(transport,
-				append([]httpcache.Option{
-					httpcache.WithFilterer(
-						httpcache.MethodFilterer(http.MethodGet),
-					),
-					httpcache.WithKeyer(
-						httpcache.JointKeyer(
-							httpcache.MethodKeyer(),
-							httpcache.PathKeyer(),
-						),
-					),
-				}, opts...)...,
-			),
-		}),
-		cliv4: ghv4.NewClient(&http.Client{
-			Transport: httpcache.NewRoundTripper(transport,
-				append([]httpcache.Option{
-					httpcache.WithFilterer(
-						httpcache.MethodFilterer(http.MethodPost),
-					),
-					httpcache.WithKeyer(
-						httpcache.JointKeyer(
-							httpcache.MethodKeyer(),
-							httpcache.PathKeyer(),
-							httpcache.BodyKeyer(),
-						),
-					),
-				}, opts...)...,
-			),
-		}),
-	}
-}
-
-type Source struct {
-	cliv3 *ghv3.Client
-	cliv4 *ghv4.Client
-}
-
-func (s *Source) Stat(ctx context.Context, username string) (*Stat, error) {
 	var query struct {
 		User struct {
-			Repositories struct {
-				Nodes []struct {
-					StargazerCount ghv4.Int
-					ForkCount      ghv4.Int
-					IsFork         ghv4.Boolean
-				}
-			} `graphql:"repositories(first: 100, ownerAffiliations: OWNER, orderBy: {direction: DESC, field: STARGAZERS})"`
 			Contributions struct {
 				TotalCommitContributions            ghv4.Int
 				TotalPullRequestReviewContributions ghv4.Int
 				TotalPullRequestContributions       ghv4.Int
 				TotalIssueContributions             ghv4.Int
-			} `graphql:"contributionsCollection(from: $from)"`
+			} `graphql:"contributionsCollection(from: $from, to: $to)"`
+		} `graphql:"user(login: $username)"`
+	}
+	from := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	variables := map[string]interface{}{
+		"from":     ghv4.DateTime{Time: from},
+		"to":       ghv4.DateTime{Time: from.AddDate(1, 0, 0)},
+		"username": ghv4.String(username),
+	}
+	err := s.cliv4.Query(ctx, &query, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	stat = &Stat{}
+	stat.Commits = int(query.User.Contributions.TotalCommitContributions)
+	stat.Reviews = int(query.User.Contributions.TotalPullRequestReviewContributions)
+	stat.PullRequests = int(query.User.Contributions.TotalPullRequestContributions)
+	stat.Issues = int(query.User.Contributions.TotalIssueContributions)
+
+	s.mut.Lock()
+	if s.years == nil {
+		s.years = map[string]*Stat{}
+	}
+	s.years[key] = stat
+	s.mut.Unlock()
+	return stat, nil
+}
+
+// stat returns the stat and the years the user has been making contributions
+func (s *GitHub) stat(ctx context.Context, username string, from, to time.Time) (*Stat, []int, error) {
+	ctx = withCacheClass(ctx, CacheStats)
+	from, to, err := contributionsWindow(from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var query struct {
+		User struct {
+			Contributions struct {
+				TotalCommitContributions            ghv4.Int
+				TotalPullRequestReviewContributions ghv4.Int
+				TotalPullRequestContributions       ghv4.Int
+				TotalIssueContributions             ghv4.Int
+				ContributionYears                   []ghv4.Int
+			} `graphql:"contributionsCollection(from: $from, to: $to)"`
 			ContributedTo struct {
 				TotalCount ghv4.Int
 			} `graphql:"repositoriesContributedTo(first: 0)"`
 			Name ghv4.String
 		} `graphql:"user(login: $username)"`
 	}
-	now := time.Now().UTC()
 	variables := map[string]interface{}{
-		"from":     ghv4.DateTime{now.AddDate(-1, 0, 0)},
+		"from":     ghv4.DateTime{Time: from},
+		"to":       ghv4.DateTime{Time: to},
 		"username": ghv4.String(username),
 	}
 
-	err := s.cliv4.Query(ctx, &query, variables)
+	err = s.cliv4.Query(ctx, &query, variables)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	repos, err := s.Repositories(ctx, username)
+	if err != nil {
+		return nil, nil, err
 	}
+
 	stat := Stat{}
 	stat.Name = string(query.User.Name)
-	for _, repo := range query.User.Repositories.Nodes {
-		stat.Stars += int(repo.StargazerCount)
+	for _, repo := range repos {
+		stat.Stars += repo.Stars
 		if !repo.IsFork {
-			stat.Forks += int(repo.ForkCount)
+			stat.Forks += repo.Forks
 		}
 	}
 
@@ -190,10 +347,74 @@ func (s *Source) Stat(ctx context.Context, username string) (*Stat, error) {
 	stat.PullRequests = int(query.User.Contributions.TotalPullRequestContributions)
 	stat.Issues = int(query.User.Contributions.TotalIssueContributions)
 	stat.ContributedTo = int(query.User.ContributedTo.TotalCount)
-	return &stat, nil
+
+	years := make([]int, 0, len(query.User.Contributions.ContributionYears))
+	for _, year := range query.User.Contributions.ContributionYears {
+		years = append(years, int(year))
+	}
+	return &stat, years, nil
+}
+
+func (s *GitHub) Repositories(ctx context.Context, username string) ([]*Repository, error) {
+	ctx = withCacheClass(ctx, CacheRepositories)
+	type repositories struct {
+		User struct {
+			Repositories struct {
+				TotalCount ghv4.Int
+				PageInfo   struct {
+					HasNextPage ghv4.Boolean
+					EndCursor   ghv4.String
+				}
+				Nodes []struct {
+					NameWithOwner  ghv4.String
+					StargazerCount ghv4.Int
+					ForkCount      ghv4.Int
+					IsFork         ghv4.Boolean
+					IsArchived     ghv4.Boolean
+				}
+			} `graphql:"repositories(first: $size, after: $after, ownerAffiliations: OWNER, orderBy: {direction: DESC, field: STARGAZERS})"`
+		} `graphql:"user(login: $username)"`
+	}
+	variables := map[string]interface{}{
+		"username": ghv4.String(username),
+		"size":     ghv4.Int(MaxPageSize),
+		"after":    (*ghv4.String)(nil),
+	}
+
+	var repos []*Repository
+	for {
+		var query repositories
+		err := s.cliv4.Query(ctx, &query, variables)
+		if err != nil {
+			return nil, err
+		}
+		if repos == nil {
+			repos = make([]*Repository, 0, int(query.User.Repositories.TotalCount))
+		}
+		for _, r := range query.User.Repositories.Nodes {
+			repos = append(repos, &Repository{
+				Name:       string(r.NameWithOwner),
+				Stars:      int(r.StargazerCount),
+				Forks:      int(r.ForkCount),
+				IsFork:     bool(r.IsFork),
+				IsArchived: bool(r.IsArchived),
+			})
+		}
+		if !query.User.Repositories.PageInfo.HasNextPage {
+			break
+		}
+		variables["after"] = ghv4.String(query.User.Repositories.PageInfo.EndCursor)
+	}
+	return repos, nil
 }
 
-func (s *Source) OrgStat(ctx context.Context, username string, org string) (*OrgStat, error) {
+func (s *GitHub) OrgStat(ctx context.Context, username string, org string, from, to time.Time) (*OrgStat, error) {
+	ctx = withCacheClass(ctx, CacheStats)
+	from, to, err := contributionsWindow(from, to)
+	if err != nil {
+		return nil, err
+	}
+
 	// Can't got Organization ID in API v4
 	o, _, err := s.cliv3.Organizations.Get(ctx, org)
 	if err != nil {
@@ -206,13 +427,13 @@ func (s *Source) OrgStat(ctx context.Context, username string, org string) (*Org
 				TotalPullRequestReviewContributions ghv4.Int
 				TotalPullRequestContributions       ghv4.Int
 				TotalIssueContributions             ghv4.Int
-			} `graphql:"contributionsCollection(from: $from, organizationID: $orgID)"`
+			} `graphql:"contributionsCollection(from: $from, to: $to, organizationID: $orgID)"`
 		} `graphql:"user(login: $username)"`
 	}
 
-	now := time.Now().UTC()
 	variables := map[string]interface{}{
-		"from":     ghv4.DateTime{now.AddDate(-1, 0, 0)},
+		"from":     ghv4.DateTime{Time: from},
+		"to":       ghv4.DateTime{Time: to},
 		"orgID":    ghv4.ID(*o.NodeID),
 		"username": ghv4.String(username),
 	}
@@ -223,8 +444,11 @@ func (s *Source) OrgStat(ctx context.Context, username string, org string) (*Org
 	}
 
 	stat := OrgStat{}
-	stat.LogoURL = *o.AvatarURL
-	stat.Name = *o.Name
+	stat.LogoURL = o.GetAvatarURL()
+	stat.Name = o.GetName()
+	if stat.Name == "" {
+		stat.Name = org
+	}
 	stat.Commits = int(query.User.Contributions.TotalCommitContributions)
 	stat.Reviews = int(query.User.Contributions.TotalPullRequestReviewContributions)
 	stat.PullRequests = int(query.User.Contributions.TotalPullRequestContributions)
@@ -232,7 +456,21 @@ func (s *Source) OrgStat(ctx context.Context, username string, org string) (*Org
 	return &stat, nil
 }
 
-func (s *Source) CommitCounter(ctx context.Context, username string) (int, error) {
+// contributionsWindow is the time window of a contributions collection, which can't exceed one year
+func contributionsWindow(from, to time.Time) (time.Time, time.Time, error) {
+	from, to = timeWindow(from, to)
+	// The window slightly exceeding one year, like a span of one year in the local time, is clamped
+	if to.After(from.AddDate(1, 0, 0)) && !to.After(from.AddDate(1, 0, 1)) {
+		from = to.AddDate(-1, 0, 0)
+	}
+	if to.After(from.AddDate(1, 0, 0)) {
+		return from, to, fmt.Errorf("the time window from %s to %s exceeds one year", from.Format(time.DateOnly), to.Format(time.DateOnly))
+	}
+	return from, to, nil
+}
+
+func (s *GitHub) CommitCounter(ctx context.Context, username string) (int, error) {
+	ctx = withCacheClass(ctx, CacheStats)
 	result, _, err := s.cliv3.Search.Commits(ctx, fmt.Sprintf("author:%q", username), &ghv3.SearchOptions{
 		ListOptions: ghv3.ListOptions{PerPage: 1},
 	})
@@ -242,72 +480,483 @@ func (s *Source) CommitCounter(ctx context.Context, username string) (int, error
 	return *result.Total, nil
 }
 
-type PullRequest struct {
-	Username     string
-	Title        string
-	URL          *url.URL
-	BaseRef      string
-	State        string
-	Additions    int
-	Deletions    int
-	Commits      int
-	ChangedFiles int
-	ChangeSize   string
-	CreatedAt    time.Time
-	ClosedAt     time.Time
-	MergedAt     time.Time
-	UpdatedAt    time.Time
-	Labels       []string
-	SortTime     time.Time
-}
-
-type PullRequestCallback func(pr *PullRequest) bool
-
-func (s *Source) PullRequests(ctx context.Context, username string, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...PullRequestCallback) ([]*PullRequest, error) {
-	var pageSize = MaxPageSize
-	if size >= 0 && pageSize > size {
-		pageSize = size
+// githubPullRequest is the fields of the pull requests queried
+type githubPullRequest struct {
+	Author struct {
+		Login ghv4.String
+	}
+	Repository struct {
+		NameWithOwner ghv4.String
+	}
+	Number       ghv4.Int
+	Title        ghv4.String
+	URL          ghv4.URI
+	BaseRefName  ghv4.String
+	State        ghv4.PullRequestState
+	Additions    ghv4.Int
+	Deletions    ghv4.Int
+	ChangedFiles ghv4.Int
+	CreatedAt    ghv4.DateTime
+	ClosedAt     ghv4.DateTime
+	MergedAt     ghv4.DateTime
+	UpdatedAt    ghv4.DateTime
+	Commits      struct {
+		TotalCount ghv4.Int
+		Nodes      []struct {
+			Commit githubCommit
+		}
+	} `graphql:"commits(last: 1)"`
+	MergeCommit struct {
+		githubCommit
+		Parents struct {
+			TotalCount ghv4.Int
+		}
+	}
+	Labels struct {
+		TotalCount ghv4.Int
+		Nodes      []struct {
+			Name ghv4.String
+		}
+	} `graphql:"labels(first: 100)"`
+	IsDraft        ghv4.Boolean
+	ReviewDecision ghv4.PullRequestReviewDecision
+	MergedBy       struct {
+		Login ghv4.String
+	}
+	Comments struct {
+		TotalCount ghv4.Int
+	}
+	ReviewRequests struct {
+		Nodes []struct {
+			RequestedReviewer struct {
+				User struct {
+					Login ghv4.String
+				} `graphql:"... on User"`
+				Team struct {
+					CombinedSlug ghv4.String
+				} `graphql:"... on Team"`
+			}
+		}
+	} `graphql:"reviewRequests(first: 20)"`
+	LatestOpinionatedReviews struct {
+		Nodes []struct {
+			Author struct {
+				Login ghv4.String
+			}
+			State ghv4.PullRequestReviewState
+		}
+	} `graphql:"latestOpinionatedReviews(first: 20, writersOnly: true)"`
+	// The first reviews, some of them may be the replies of the author
+	Reviews struct {
+		Nodes []struct {
+			Author struct {
+				Login ghv4.String
+			}
+			SubmittedAt ghv4.DateTime
+		}
+	} `graphql:"reviews(first: 10)"`
+}
+
+type githubCommit struct {
+	Oid             ghv4.GitObjectID
+	AuthoredDate    ghv4.DateTime
+	MessageHeadline ghv4.String
+}
+
+// mergeStrategy infers how the pull request is merged and the number of the commits landed on the base branch,
+// as GitHub doesn't record the merge method. The merge commit has two parents, the rebased commit keeps
+// the author date and the message of the last commit, and the squashed commit is authored when it's merged.
+// A single commit squashed with its author date and message kept looks rebased, and both land one commit,
+// so the pull request of a single commit is reported squashed unless it's merged with a merge commit.
+func (r *githubPullRequest) mergeStrategy() (PullRequestMergeMethod, int) {
+	commits := int(r.Commits.TotalCount)
+	merge := r.MergeCommit
+	if r.State != ghv4.PullRequestStateMerged || merge.Oid == "" {
+		return "", commits
+	}
+	if merge.Parents.TotalCount > 1 {
+		return PullRequestMergeMethodMerge, commits
+	}
+	if commits > 1 && len(r.Commits.Nodes) != 0 {
+		last := r.Commits.Nodes[0].Commit
+		if last.Oid == merge.Oid ||
+			(last.AuthoredDate.Equal(merge.AuthoredDate.Time) && last.MessageHeadline == merge.MessageHeadline) {
+			return PullRequestMergeMethodRebase, commits
+		}
+	}
+	return PullRequestMergeMethodSquash, 1
+}
+
+func (r *githubPullRequest) conv(states []PullRequestState) *PullRequest {
+	strategy, commits := r.mergeStrategy()
+	p := PullRequest{
+		Username:     string(r.Author.Login),
+		Repository:   string(r.Repository.NameWithOwner),
+		Number:       int(r.Number),
+		Title:        string(r.Title),
+		URL:          r.URL.URL,
+		BaseRef:      string(r.BaseRefName),
+		State:        string(r.State),
+		Additions:    int(r.Additions),
+		Deletions:    int(r.Deletions),
+		ChangedFiles: int(r.ChangedFiles),
+		ChangeSize:   changeSize(int(r.Additions + r.Deletions)),
+		Commits:      commits,
+		CreatedAt:    r.CreatedAt.Time,
+		ClosedAt:     r.ClosedAt.Time,
+		MergedAt:     r.MergedAt.Time,
+		UpdatedAt:    r.UpdatedAt.Time,
+		SortTime:     r.UpdatedAt.Time,
+	}
+	if len(r.Labels.Nodes) != 0 {
+		labels := make([]string, 0, len(r.Labels.Nodes))
+		for _, label := range r.Labels.Nodes {
+			labels = append(labels, string(label.Name))
+		}
+		p.Labels = labels
+	}
+	p.MergeStrategy = string(strategy)
+	p.IsDraft = bool(r.IsDraft)
+	p.ReviewDecision = string(r.ReviewDecision)
+	p.MergedBy = string(r.MergedBy.Login)
+	p.Comments = int(r.Comments.TotalCount)
+	for _, node := range r.ReviewRequests.Nodes {
+		reviewer := node.RequestedReviewer
+		switch {
+		case reviewer.User.Login != "":
+			p.RequestedReviewers = append(p.RequestedReviewers, string(reviewer.User.Login))
+		case reviewer.Team.CombinedSlug != "":
+			p.RequestedReviewers = append(p.RequestedReviewers, string(reviewer.Team.CombinedSlug))
+		}
+	}
+	for _, review := range r.LatestOpinionatedReviews.Nodes {
+		if review.State == ghv4.PullRequestReviewStateApproved {
+			p.Approvers = append(p.Approvers, string(review.Author.Login))
+		}
+	}
+	for _, review := range r.Reviews.Nodes {
+		if review.Author.Login != r.Author.Login && !review.SubmittedAt.IsZero() {
+			p.FirstReviewAt = review.SubmittedAt.Time
+			break
+		}
+	}
+	setSortTime(&p, states)
+	return &p
+}
+
+// githubPage is a page of a connection
+type githubPage[N any] struct {
+	TotalCount ghv4.Int
+	PageInfo   struct {
+		HasNextPage ghv4.Boolean
+		EndCursor   ghv4.String
 	}
+	Nodes []N
+}
+
+// githubPullRequestPage is a page of a pull request connection
+type githubPullRequestPage = githubPage[githubPullRequest]
+
+// githubPullRequests walks the pages fetched after the cursor, until the size is reached or a callback stops it
+func githubPullRequests(ctx context.Context, states []PullRequestState, size int, cbs []PullRequestCallback, fetch func(pageSize int, after *ghv4.String) (*githubPullRequestPage, error)) ([]*PullRequest, error) {
+	return githubList(ctx, size, cbs, func(r *githubPullRequest) *PullRequest {
+		return r.conv(states)
+	}, fetch)
+}
+
+// iterGitHubPullRequests iterates the pages fetched after the cursor until the size is reached,
+// the next page is fetched when the previous one is consumed.
+func iterGitHubPullRequests(ctx context.Context, states []PullRequestState, size int, fetch func(pageSize int, after *ghv4.String) (*githubPullRequestPage, error)) iter.Seq2[*PullRequest, error] {
+	return iterGitHubPages(ctx, size, func(r *githubPullRequest) *PullRequest {
+		return r.conv(states)
+	}, fetch)
+}
+
+// githubList walks the pages of the connection fetched after the cursor, until the size is reached or a callback stops it
+func githubList[N any, T any, C ~func(T) bool](ctx context.Context, size int, cbs []C, conv func(*N) T, fetch func(pageSize int, after *ghv4.String) (*githubPage[N], error)) ([]T, error) {
+	items := []T{}
+	for item, err := range iterGitHubPages(ctx, size, conv, fetch) {
+		if err != nil {
+			return nil, err
+		}
+		for _, cb := range cbs {
+			if !cb(item) {
+				return items, nil
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// iterGitHubPages iterates the nodes of the pages fetched after the cursor until the size is reached,
+// the next page is fetched when the previous one is consumed.
+func iterGitHubPages[N any, T any](ctx context.Context, size int, conv func(*N) T, fetch func(pageSize int, after *ghv4.String) (*githubPage[N], error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		var pageSize = MaxPageSize
+		if size >= 0 && pageSize > size {
+			pageSize = size
+		}
+
+		count := 0
+		var after *ghv4.String
+		for {
+			err := ctx.Err()
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			pageSize := pageSize
+			if size >= 0 && pageSize+count > size {
+				pageSize = size - count
+			}
+			page, err := fetch(pageSize, after)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			for i := range page.Nodes {
+				if !yield(conv(&page.Nodes[i]), nil) {
+					return
+				}
+				count++
+			}
+			cursor := page.PageInfo.EndCursor
+			if !page.PageInfo.HasNextPage || cursor == "" ||
+				(size >= 0 && count >= size) {
+				return
+			}
+			after = &cursor
+		}
+	}
+}
+
+func (s *GitHub) PullRequests(ctx context.Context, username string, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...PullRequestCallback) ([]*PullRequest, error) {
+	ctx = withCacheClass(ctx, CachePullRequests)
 	if len(states) == 0 {
 		states = []PullRequestState{PullRequestStateOpen}
 	}
+	return githubPullRequests(ctx, states, size, cbs, s.userPullRequestPages(ctx, username, states, orderField, orderDirection))
+}
+
+// IterPullRequests iterates the pull requests created by the user, the pages are fetched lazily
+func (s *GitHub) IterPullRequests(ctx context.Context, username string, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection) iter.Seq2[*PullRequest, error] {
+	ctx = withCacheClass(ctx, CachePullRequests)
+	if len(states) == 0 {
+		states = []PullRequestState{PullRequestStateOpen}
+	}
+	return iterGitHubPullRequests(ctx, states, -1, s.userPullRequestPages(ctx, username, states, orderField, orderDirection))
+}
+
+// userPullRequestPages returns the fetch of the pages of the pull requests created by the user
+func (s *GitHub) userPullRequestPages(ctx context.Context, username string, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection) func(pageSize int, after *ghv4.String) (*githubPullRequestPage, error) {
+	return func(pageSize int, after *ghv4.String) (*githubPullRequestPage, error) {
+		var query struct {
+			User struct {
+				PullRequests githubPullRequestPage `graphql:"pullRequests(first: $size, states: $states, after: $after, orderBy: $orderBy)"`
+			} `graphql:"user(login: $username)"`
+		}
+		variables := map[string]interface{}{
+			"username": ghv4.String(username),
+			"states":   states,
+			"size":     ghv4.Int(pageSize),
+			"after":    after,
+			"orderBy": ghv4.IssueOrder{
+				Field:     orderField,
+				Direction: orderDirection,
+			},
+		}
+		err := s.cliv4.Query(ctx, &query, variables)
+		if err != nil {
+			return nil, err
+		}
+		return &query.User.PullRequests, nil
+	}
+}
+
+// ScopedPullRequests lists the pull requests of the repository by its connection, or of the organization or the query by the search,
+// the search finds at most 1000 pull requests.
+func (s *GitHub) ScopedPullRequests(ctx context.Context, scope Scope, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...PullRequestCallback) ([]*PullRequest, error) {
+	ctx = withCacheClass(ctx, CachePullRequests)
+	if len(states) == 0 {
+		states = []PullRequestState{PullRequestStateOpen}
+	}
+
+	switch scope.Kind {
+	case ScopeRepository:
+		owner, name, _ := strings.Cut(scope.Name, "/")
+		return githubPullRequests(ctx, states, size, cbs, func(pageSize int, after *ghv4.String) (*githubPullRequestPage, error) {
+			var query struct {
+				Repository struct {
+					PullRequests githubPullRequestPage `graphql:"pullRequests(first: $size, states: $states, after: $after, orderBy: $orderBy)"`
+				} `graphql:"repository(owner: $owner, name: $name)"`
+			}
+			variables := map[string]interface{}{
+				"owner":  ghv4.String(owner),
+				"name":   ghv4.String(name),
+				"states": states,
+				"size":   ghv4.Int(pageSize),
+				"after":  after,
+				"orderBy": ghv4.IssueOrder{
+					Field:     orderField,
+					Direction: orderDirection,
+				},
+			}
+			err := s.cliv4.Query(ctx, &query, variables)
+			if err != nil {
+				return nil, err
+			}
+			return &query.Repository.PullRequests, nil
+		})
+	case ScopeOrg:
+		return s.searchPullRequests(ctx, "org:"+scope.Name, states, orderField, orderDirection, size, cbs)
+	case ScopeSearch:
+		return s.searchPullRequests(ctx, scope.Name, states, orderField, orderDirection, size, cbs)
+	}
+	return nil, fmt.Errorf("github scope %s: %w", scope, ErrNotSupported)
+}
+
+// searchPullRequests lists the pull requests found by the search query
+func (s *GitHub) searchPullRequests(ctx context.Context, q string, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs []PullRequestCallback) ([]*PullRequest, error) {
+	q, err := githubSearchQuery(q, states, orderField, orderDirection)
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		return []*PullRequest{}, nil
+	}
+
+	// The states which can't be searched together are filtered here
+	listed := func(pr *PullRequest) bool {
+		for _, state := range states {
+			if string(state) == pr.State {
+				return true
+			}
+		}
+		return false
+	}
+	prs := []*PullRequest{}
+	_, err = githubPullRequests(ctx, states, -1, []PullRequestCallback{func(pr *PullRequest) bool {
+		if !listed(pr) {
+			return true
+		}
+		for _, cb := range cbs {
+			if !cb(pr) {
+				return false
+			}
+		}
+		prs = append(prs, pr)
+		return size < 0 || len(prs) < size
+	}}, func(pageSize int, after *ghv4.String) (*githubPullRequestPage, error) {
+		var query struct {
+			Search struct {
+				IssueCount ghv4.Int
+				PageInfo   struct {
+					HasNextPage ghv4.Boolean
+					EndCursor   ghv4.String
+				}
+				Nodes []struct {
+					PullRequest githubPullRequest `graphql:"... on PullRequest"`
+				}
+			} `graphql:"search(query: $query, type: ISSUE, first: $size, after: $after)"`
+		}
+		variables := map[string]interface{}{
+			"query": ghv4.String(q),
+			"size":  ghv4.Int(pageSize),
+			"after": after,
+		}
+		err := s.cliv4.Query(ctx, &query, variables)
+		if err != nil {
+			return nil, err
+		}
+		page := &githubPullRequestPage{
+			TotalCount: query.Search.IssueCount,
+			PageInfo:   query.Search.PageInfo,
+			Nodes:      make([]githubPullRequest, 0, len(query.Search.Nodes)),
+		}
+		for _, node := range query.Search.Nodes {
+			page.Nodes = append(page.Nodes, node.PullRequest)
+		}
+		return page, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prs, nil
+}
+
+// githubSearchQuery qualifies the search query with the states and the order,
+// the order of the query is kept if it has one.
+func githubSearchQuery(q string, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection) (string, error) {
+	isPR, sorted := false, false
+	for _, field := range strings.Fields(q) {
+		switch {
+		case field == "is:pr", field == "type:pr":
+			isPR = true
+		case strings.HasPrefix(field, "sort:"):
+			sorted = true
+		}
+	}
+	if !isPR {
+		q += " is:pr"
+	}
+	// The states are qualified only if there is one, the others are filtered after the search
+	if len(states) == 1 {
+		switch states[0] {
+		case PullRequestStateOpen:
+			q += " is:open"
+		case PullRequestStateClosed:
+			q += " is:closed is:unmerged"
+		case PullRequestStateMerged:
+			q += " is:merged"
+		}
+	}
+	if sorted {
+		return q, nil
+	}
+	var sort string
+	switch orderField {
+	case IssueOrderFieldCreatedAt:
+		sort = "created"
+	case IssueOrderFieldUpdatedAt:
+		sort = "updated"
+	case IssueOrderFieldComments:
+		sort = "comments"
+	default:
+		return "", fmt.Errorf("github search order by %s: %w", orderField, ErrNotSupported)
+	}
+	return q + " sort:" + sort + "-" + strings.ToLower(string(orderDirection)), nil
+}
+
+func (s *GitHub) Issues(ctx context.Context, username string, states []IssueState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...IssueCallback) ([]*Issue, error) {
+	ctx = withCacheClass(ctx, CacheIssues)
+	if len(states) == 0 {
+		states = []IssueState{IssueStateOpen}
+	}
 
 	orderBy := ghv4.IssueOrder{
 		Field:     orderField,
 		Direction: orderDirection,
 	}
-	type pr struct {
+	type issue struct {
 		Author struct {
 			Login ghv4.String
 		}
-		Title        ghv4.String
-		URL          ghv4.URI
-		BaseRefName  ghv4.String
-		State        ghv4.PullRequestState
-		Additions    ghv4.Int
-		Deletions    ghv4.Int
-		ChangedFiles ghv4.Int
-		CreatedAt    ghv4.DateTime
-		ClosedAt     ghv4.DateTime
-		MergedAt     ghv4.DateTime
-		UpdatedAt    ghv4.DateTime
-		Commits      struct {
+		Repository struct {
+			NameWithOwner ghv4.String
+		}
+		Number   ghv4.Int
+		Title    ghv4.String
+		URL      ghv4.URI
+		State    ghv4.IssueState
+		Comments struct {
 			TotalCount ghv4.Int
-			//	Nodes      []struct {
-			//		Commit struct {
-			//			CommitUrl ghv4.URI
-			//		}
-			//	}
-		} // `graphql:"commits(first: 1)"`
-		MergeCommit struct {
-			Parents struct {
-				TotalCount ghv4.Int
-				//		Nodes      []struct {
-				//			CommitUrl ghv4.URI
-				//		}
-			} // `graphql:"parents(first: 2)"`
 		}
-		Labels struct {
+		CreatedAt ghv4.DateTime
+		ClosedAt  ghv4.DateTime
+		UpdatedAt ghv4.DateTime
+		Labels    struct {
 			TotalCount ghv4.Int
 			Nodes      []struct {
 				Name ghv4.String
@@ -315,144 +964,144 @@ func (s *Source) PullRequests(ctx context.Context, username string, states []Pul
 		} `graphql:"labels(first: 100)"`
 	}
 
-	conv := func(r *pr) *PullRequest {
-		commits := int(r.Commits.TotalCount)
-		if commits > 1 {
-			if r.MergeCommit.Parents.TotalCount == 1 {
-				commits = 1
-				//} else if len(r.Commits.Nodes) != 0 {
-				//	commit := r.Commits.Nodes[0].Commit.CommitUrl
-				//	for _, node := range r.MergeCommit.Parents.Nodes {
-				//		if node.CommitUrl == commit {
-				//			commits = 1
-				//			break
-				//		}
-				//	}
-			}
-		}
-		p := PullRequest{
-			Username:     string(r.Author.Login),
-			Title:        string(r.Title),
-			URL:          r.URL.URL,
-			BaseRef:      string(r.BaseRefName),
-			State:        string(r.State),
-			Additions:    int(r.Additions),
-			Deletions:    int(r.Deletions),
-			ChangedFiles: int(r.ChangedFiles),
-			ChangeSize:   changeSize(int(r.Additions + r.Deletions)),
-			Commits:      commits,
-			CreatedAt:    r.CreatedAt.Time,
-			ClosedAt:     r.ClosedAt.Time,
-			MergedAt:     r.MergedAt.Time,
-			UpdatedAt:    r.UpdatedAt.Time,
-			SortTime:     r.UpdatedAt.Time,
+	conv := func(r *issue) *Issue {
+		i := Issue{
+			Username:   string(r.Author.Login),
+			Repository: string(r.Repository.NameWithOwner),
+			Number:     int(r.Number),
+			Title:      string(r.Title),
+			URL:        r.URL.URL,
+			State:      string(r.State),
+			Comments:   int(r.Comments.TotalCount),
+			CreatedAt:  r.CreatedAt.Time,
+			ClosedAt:   r.ClosedAt.Time,
+			UpdatedAt:  r.UpdatedAt.Time,
+			SortTime:   r.UpdatedAt.Time,
 		}
 		if len(r.Labels.Nodes) != 0 {
 			labels := make([]string, 0, len(r.Labels.Nodes))
 			for _, label := range r.Labels.Nodes {
 				labels = append(labels, string(label.Name))
 			}
-			p.Labels = labels
-		}
-		if len(states) == 1 {
-			switch states[0] {
-			case PullRequestStateMerged:
-				p.SortTime = p.MergedAt
-			case PullRequestStateClosed:
-				p.SortTime = p.ClosedAt
-			case PullRequestStateOpen:
-				p.SortTime = p.CreatedAt
-			}
-		}
-		return &p
-	}
-	var query struct {
-		User struct {
-			PullRequests struct {
-				TotalCount ghv4.Int
-				PageInfo   struct {
-					HasNextPage ghv4.Boolean
-					EndCursor   ghv4.String
-				}
-				Nodes []pr
-			} `graphql:"pullRequests(first: $size, states: $states, after: $after, orderBy: $orderBy)"`
-		} `graphql:"user(login: $username)"`
-	}
-	variables := map[string]interface{}{
-		"username": ghv4.String(username),
-		"states":   states,
-		"size":     ghv4.Int(pageSize),
-		"after":    (*ghv4.String)(nil),
-		"orderBy":  orderBy,
-	}
-	err := s.cliv4.Query(ctx, &query, variables)
-	if err != nil {
-		return nil, err
-	}
-
-	count := int(query.User.PullRequests.TotalCount)
-	next := bool(query.User.PullRequests.PageInfo.HasNextPage)
-	cursor := string(query.User.PullRequests.PageInfo.EndCursor)
-	prs := make([]*PullRequest, 0, count)
-	for _, r := range query.User.PullRequests.Nodes {
-		pr := conv(&r)
-		if len(cbs) != 0 {
-			for _, cb := range cbs {
-				if !cb(pr) {
-					return prs, nil
-				}
-			}
+			i.Labels = labels
 		}
-		prs = append(prs, pr)
+		setIssueSortTime(&i, states)
+		return &i
 	}
-
-	for next && cursor != "" &&
-		(size < 0 || len(prs) < size) {
-		pageSize := pageSize
-		if size >= 0 && pageSize+len(prs) > size {
-			pageSize = size - len(prs)
+	return githubList(ctx, size, cbs, conv, func(pageSize int, after *ghv4.String) (*githubPage[issue], error) {
+		var query struct {
+			User struct {
+				Issues githubPage[issue] `graphql:"issues(first: $size, states: $states, after: $after, orderBy: $orderBy)"`
+			} `graphql:"user(login: $username)"`
 		}
 		variables := map[string]interface{}{
 			"username": ghv4.String(username),
 			"states":   states,
 			"size":     ghv4.Int(pageSize),
-			"after":    ghv4.String(cursor),
+			"after":    after,
 			"orderBy":  orderBy,
 		}
 		err := s.cliv4.Query(ctx, &query, variables)
 		if err != nil {
 			return nil, err
 		}
-		for _, r := range query.User.PullRequests.Nodes {
-			pr := conv(&r)
-			if len(cbs) != 0 {
+		return &query.User.Issues, nil
+	})
+}
+
+// Reviews lists the reviews from the contributions collection, which spans at most one year per query
+func (s *GitHub) Reviews(ctx context.Context, username string, from, to time.Time, size int, cbs ...ReviewCallback) ([]*Review, error) {
+	ctx = withCacheClass(ctx, CacheReviews)
+	var pageSize = MaxPageSize
+	if size >= 0 && pageSize > size {
+		pageSize = size
+	}
+	from, to = timeWindow(from, to)
+
+	type contribution struct {
+		PullRequestReview struct {
+			State       ghv4.PullRequestReviewState
+			SubmittedAt ghv4.DateTime
+		}
+		PullRequest struct {
+			Number      ghv4.Int
+			Title       ghv4.String
+			URL         ghv4.URI
+			BaseRefName ghv4.String
+			Author      struct {
+				Login ghv4.String
+			}
+		}
+		Repository struct {
+			NameWithOwner ghv4.String
+		}
+	}
+	var query struct {
+		User struct {
+			Contributions struct {
+				PullRequestReviewContributions struct {
+					PageInfo struct {
+						HasNextPage ghv4.Boolean
+						EndCursor   ghv4.String
+					}
+					Nodes []contribution
+				} `graphql:"pullRequestReviewContributions(first: $size, after: $after, orderBy: {direction: DESC})"`
+			} `graphql:"contributionsCollection(from: $from, to: $to)"`
+		} `graphql:"user(login: $username)"`
+	}
+
+	reviews := []*Review{}
+	for end := to; end.After(from) && (size < 0 || len(reviews) < size); end = end.AddDate(-1, 0, 0) {
+		begin := end.AddDate(-1, 0, 0)
+		if begin.Before(from) {
+			begin = from
+		}
+		var cursor *ghv4.String
+		for {
+			pageSize := pageSize
+			if size >= 0 && pageSize+len(reviews) > size {
+				pageSize = size - len(reviews)
+			}
+			variables := map[string]interface{}{
+				"username": ghv4.String(username),
+				"from":     ghv4.DateTime{Time: begin},
+				"to":       ghv4.DateTime{Time: end},
+				"size":     ghv4.Int(pageSize),
+				"after":    cursor,
+			}
+			err := s.cliv4.Query(ctx, &query, variables)
+			if err != nil {
+				return nil, err
+			}
+
+			contributions := query.User.Contributions.PullRequestReviewContributions
+			for _, r := range contributions.Nodes {
+				review := &Review{
+					Username:    username,
+					Repository:  string(r.Repository.NameWithOwner),
+					Number:      int(r.PullRequest.Number),
+					Title:       string(r.PullRequest.Title),
+					URL:         r.PullRequest.URL.URL,
+					BaseRef:     string(r.PullRequest.BaseRefName),
+					Author:      string(r.PullRequest.Author.Login),
+					State:       string(r.PullRequestReview.State),
+					SubmittedAt: r.PullRequestReview.SubmittedAt.Time,
+				}
 				for _, cb := range cbs {
-					if !cb(pr) {
-						return prs, nil
+					if !cb(review) {
+						return reviews, nil
 					}
 				}
+				reviews = append(reviews, review)
 			}
-			prs = append(prs, pr)
-		}
-		next = bool(query.User.PullRequests.PageInfo.HasNextPage)
-		cursor = string(query.User.PullRequests.PageInfo.EndCursor)
-	}
-	return prs, nil
-}
 
-func changeSize(i int) string {
-	switch {
-	case i < 10:
-		return "XS"
-	case i < 30:
-		return "S"
-	case i < 100:
-		return "M"
-	case i < 500:
-		return "L"
-	case i < 1000:
-		return "XL"
-	default:
-		return "XXL"
+			if !contributions.PageInfo.HasNextPage || contributions.PageInfo.EndCursor == "" ||
+				(size >= 0 && len(reviews) >= size) {
+				break
+			}
+			next := contributions.PageInfo.EndCursor
+			cursor = &next
+		}
 	}
+	return reviews, nil
 }
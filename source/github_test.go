@@ -0,0 +1,527 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ghv4 "github.com/shurcooL/githubv4"
+)
+
+func TestIntervalRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	interval := 20 * time.Millisecond
+	cli := &http.Client{Transport: newIntervalRequest(http.DefaultTransport, interval, 0)}
+
+	const n = 5
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i != n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := cli.Get(server.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < (n-1)*interval {
+		t.Errorf("%d requests took %s, want at least %s", n, elapsed, (n-1)*interval)
+	}
+}
+
+func TestGitHubEnterprise(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/api/v3/orgs/foo":
+			rw.Write([]byte(`{"login":"foo","node_id":"O_1"}`))
+		case "/api/graphql":
+			rw.Write([]byte(`{"data":{"user":{"contributionsCollection":{"totalCommitContributions":3}}}}`))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	for _, baseURL := range []string{server.URL, server.URL + "/", server.URL + "/api/v3"} {
+		paths = nil
+		src, err := NewGitHub(baseURL, "", CachePolicy{}, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stat, err := src.OrgStat(context.Background(), "bar", "foo", time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatalf("OrgStat() %s error = %v, paths %v", baseURL, err, paths)
+		}
+		if stat.Commits != 3 {
+			t.Errorf("OrgStat() %s commits = %d, want 3", baseURL, stat.Commits)
+		}
+	}
+
+	_, err := NewGitHub("ghe.example.com", "", CachePolicy{}, 0, 0)
+	if err == nil {
+		t.Errorf("NewGitHub() without scheme want error")
+	}
+}
+
+func TestGitHubScopedPullRequests(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		queries = append(queries, fmt.Sprint(body.Variables["query"]))
+		rw.Write([]byte(`{"data":{"search":{"issueCount":3,"pageInfo":{"hasNextPage":false},"nodes":[
+			{"number":3,"state":"OPEN","repository":{"nameWithOwner":"foo/a"}},
+			{"number":2,"state":"MERGED","repository":{"nameWithOwner":"foo/b"}},
+			{"number":1,"state":"CLOSED","repository":{"nameWithOwner":"foo/a"}}
+		]}}}`))
+	}))
+	defer server.Close()
+
+	src, err := NewGitHub(server.URL, "", CachePolicy{}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	scope := Scope{Kind: ScopeOrg, Name: "foo"}
+	prs, err := src.ScopedPullRequests(ctx, scope, []PullRequestState{PullRequestStateMerged},
+		IssueOrderFieldCreatedAt, OrderDirectionDesc, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 1 || prs[0].Number != 2 || prs[0].Repository != "foo/b" {
+		t.Errorf("ScopedPullRequests() = %v", prs)
+	}
+
+	prs, err = src.ScopedPullRequests(ctx, scope, []PullRequestState{PullRequestStateOpen, PullRequestStateClosed},
+		IssueOrderFieldUpdatedAt, OrderDirectionAsc, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 1 || prs[0].Number != 3 {
+		t.Errorf("ScopedPullRequests() = %v", prs)
+	}
+
+	want := []string{
+		"org:foo is:pr is:merged sort:created-desc",
+		"org:foo is:pr sort:updated-asc",
+	}
+	if strings.Join(queries, "\n") != strings.Join(want, "\n") {
+		t.Errorf("queries = %q, want %q", queries, want)
+	}
+}
+
+func TestGitHubIterPullRequests(t *testing.T) {
+	var afters []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		afters = append(afters, body.Variables["after"])
+		number := len(afters)
+		fmt.Fprintf(rw, `{"data":{"user":{"pullRequests":{"totalCount":3,"pageInfo":{"hasNextPage":true,"endCursor":"c%d"},"nodes":[
+			{"number":%d,"state":"OPEN","repository":{"nameWithOwner":"foo/a"}}
+		]}}}}`, number, number)
+	}))
+	defer server.Close()
+
+	src, err := NewGitHub(server.URL, "", CachePolicy{}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var numbers []int
+	for pr, err := range IterPullRequests(ctx, src, "foo", nil, IssueOrderFieldCreatedAt, OrderDirectionDesc) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		numbers = append(numbers, pr.Number)
+		if len(numbers) == 2 {
+			break
+		}
+	}
+	if fmt.Sprint(numbers) != "[1 2]" || fmt.Sprint(afters) != "[<nil> c1]" {
+		t.Errorf("numbers = %v, afters = %v, want stopped after the second page", numbers, afters)
+	}
+
+	cancel()
+	for _, err := range src.IterPullRequests(ctx, "foo", nil, IssueOrderFieldCreatedAt, OrderDirectionDesc) {
+		if err == nil {
+			t.Fatal("IterPullRequests() with canceled context, want error")
+		}
+	}
+	if len(afters) != 2 {
+		t.Errorf("requests = %d, want no more request after canceled", len(afters))
+	}
+}
+
+func TestGitHubIssues(t *testing.T) {
+	var afters []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		afters = append(afters, body.Variables["after"])
+		number := len(afters)
+		fmt.Fprintf(rw, `{"data":{"user":{"issues":{"totalCount":5,"pageInfo":{"hasNextPage":%t,"endCursor":"c%d"},"nodes":[
+			{"number":%d,"state":"CLOSED","repository":{"nameWithOwner":"foo/a"},"labels":{"nodes":[{"name":"bug"}]}}
+		]}}}}`, number < 5, number, number)
+	}))
+	defer server.Close()
+
+	src, err := NewGitHub(server.URL, "", CachePolicy{}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := src.Issues(context.Background(), "foo", []IssueState{IssueStateClosed},
+		IssueOrderFieldCreatedAt, OrderDirectionDesc, -1, func(issue *Issue) bool {
+			return issue.Number < 3
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 || issues[1].Number != 2 || issues[1].Labels[0] != "bug" {
+		t.Errorf("Issues() = %+v", issues)
+	}
+	if fmt.Sprint(afters) != "[<nil> c1 c2]" {
+		t.Errorf("afters = %v, want stopped at the third page", afters)
+	}
+}
+
+func TestGitHubLifetimeStat(t *testing.T) {
+	year := time.Now().UTC().Year()
+	counts := map[int]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if strings.Contains(body.Query, "repositories(") {
+			rw.Write([]byte(`{"data":{"user":{"repositories":{"totalCount":0,"pageInfo":{"hasNextPage":false},"nodes":[]}}}}`))
+			return
+		}
+		from, _ := time.Parse(time.RFC3339, fmt.Sprint(body.Variables["from"]))
+		counts[from.Year()]++
+		if from.Year() == year {
+			fmt.Fprintf(rw, `{"data":{"user":{"contributionsCollection":{"totalCommitContributions":1,"totalPullRequestReviewContributions":0,"totalPullRequestContributions":0,"totalIssueContributions":0,"contributionYears":[%d,%d,%d]},"repositoriesContributedTo":{"totalCount":0},"name":"Foo"}}}`,
+				year, year-1, year-2)
+			return
+		}
+		// The commits of the past years are 10 and 100
+		commits := 10
+		if from.Year() == year-2 {
+			commits = 100
+		}
+		fmt.Fprintf(rw, `{"data":{"user":{"contributionsCollection":{"totalCommitContributions":%d,"totalPullRequestReviewContributions":0,"totalPullRequestContributions":0,"totalIssueContributions":0}}}}`, commits)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	src, err := NewGitHub(server.URL, "", CachePolicy{Dir: dir}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i != 2; i++ {
+		stat, err := src.LifetimeStat(context.Background(), "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.Commits != 111 {
+			t.Errorf("LifetimeStat() commits = %d, want 111", stat.Commits)
+		}
+	}
+	if counts[year-1] != 1 || counts[year-2] != 1 {
+		t.Errorf("past years fetched %v, want once each", counts)
+	}
+
+	// The past years are served from the cache of another run
+	src, err = NewGitHub(server.URL, "", CachePolicy{Dir: dir}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat, err := src.LifetimeStat(context.Background(), "foo")
+	if err != nil || stat.Commits != 111 {
+		t.Fatalf("LifetimeStat() = %v, %v", stat, err)
+	}
+	if counts[year-1] != 1 || counts[year-2] != 1 {
+		t.Errorf("past years fetched %v, want once each", counts)
+	}
+}
+
+func TestGitHubRepositories(t *testing.T) {
+	var afters []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		afters = append(afters, body.Variables["after"])
+		if body.Variables["after"] == nil {
+			nodes := make([]string, 0, MaxPageSize)
+			for i := 0; i != MaxPageSize; i++ {
+				nodes = append(nodes, fmt.Sprintf(`{"nameWithOwner":"foo/r%d","stargazerCount":1}`, i))
+			}
+			fmt.Fprintf(rw, `{"data":{"user":{"repositories":{"totalCount":101,"pageInfo":{"hasNextPage":true,"endCursor":"c1"},"nodes":[%s]}}}}`,
+				strings.Join(nodes, ","))
+			return
+		}
+		rw.Write([]byte(`{"data":{"user":{"repositories":{"totalCount":101,"pageInfo":{"hasNextPage":false,"endCursor":"c2"},"nodes":[
+			{"nameWithOwner":"foo/last","stargazerCount":2,"forkCount":1}
+		]}}}}`))
+	}))
+	defer server.Close()
+
+	src, err := NewGitHub(server.URL, "", CachePolicy{}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos, err := src.Repositories(context.Background(), "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != MaxPageSize+1 || repos[0].Name != "foo/r0" || repos[MaxPageSize].Name != "foo/last" {
+		t.Errorf("Repositories() = %d repositories", len(repos))
+	}
+	if fmt.Sprint(afters) != "[<nil> c1]" {
+		t.Errorf("afters = %v, want the second page after c1", afters)
+	}
+}
+
+func TestGitHubReviews(t *testing.T) {
+	from := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+	// The reviews of each year window by its beginning, they're served 2 in a page at most
+	windows := map[string][]int{
+		"2023-09-01T00:00:00Z": {5, 4, 3},
+		"2023-06-01T00:00:00Z": {2, 1},
+	}
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		begin := fmt.Sprint(body.Variables["from"])
+		size := int(body.Variables["size"].(float64))
+		after := 0
+		if cursor, ok := body.Variables["after"].(string); ok {
+			after, _ = strconv.Atoi(cursor)
+		}
+		requests = append(requests, fmt.Sprintf("%s..%s/%d/%d", begin[:10], fmt.Sprint(body.Variables["to"])[:10], after, size))
+
+		numbers := windows[begin][after:]
+		if size > 2 {
+			size = 2
+		}
+		if len(numbers) > size {
+			numbers = numbers[:size]
+		}
+		nodes := []string{}
+		for _, number := range numbers {
+			nodes = append(nodes, fmt.Sprintf(`{"pullRequestReview":{"state":"APPROVED","submittedAt":"2024-01-02T03:04:05Z"},"pullRequest":{"number":%d,"title":"PR %d","baseRefName":"main","author":{"login":"bar"}},"repository":{"nameWithOwner":"foo/bar"}}`, number, number))
+		}
+		end := after + len(numbers)
+		fmt.Fprintf(rw, `{"data":{"user":{"contributionsCollection":{"pullRequestReviewContributions":{"pageInfo":{"hasNextPage":%t,"endCursor":"%d"},"nodes":[%s]}}}}}`,
+			end < len(windows[begin]), end, strings.Join(nodes, ","))
+	}))
+	defer server.Close()
+
+	src, err := NewGitHub(server.URL, "", CachePolicy{}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		size     int
+		numbers  string
+		requests string
+	}{
+		{
+			size:     -1,
+			numbers:  "[5 4 3 2 1]",
+			requests: "[2023-09-01..2024-09-01/0/100 2023-09-01..2024-09-01/2/100 2023-06-01..2023-09-01/0/100]",
+		},
+		{
+			size:     3,
+			numbers:  "[5 4 3]",
+			requests: "[2023-09-01..2024-09-01/0/3 2023-09-01..2024-09-01/2/1]",
+		},
+		{
+			size:     4,
+			numbers:  "[5 4 3 2]",
+			requests: "[2023-09-01..2024-09-01/0/4 2023-09-01..2024-09-01/2/2 2023-06-01..2023-09-01/0/1]",
+		},
+	}
+	for _, tt := range tests {
+		requests = nil
+		reviews, err := src.Reviews(context.Background(), "foo", from, to, tt.size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		numbers := []int{}
+		for _, review := range reviews {
+			numbers = append(numbers, review.Number)
+		}
+		if fmt.Sprint(numbers) != tt.numbers {
+			t.Errorf("Reviews() size %d = %v, want %s", tt.size, numbers, tt.numbers)
+		}
+		if fmt.Sprint(requests) != tt.requests {
+			t.Errorf("Reviews() size %d requests = %v, want %s", tt.size, requests, tt.requests)
+		}
+	}
+}
+
+func TestGitHubSearchQuery(t *testing.T) {
+	for q, want := range map[string]string{
+		"is:pr review-requested:@me": "is:pr review-requested:@me sort:created-desc",
+		"is:private label:security":  "is:private label:security is:pr sort:created-desc",
+		"org:foo sort:updated-asc":   "org:foo sort:updated-asc is:pr",
+	} {
+		got, err := githubSearchQuery(q, allPullRequestStates, IssueOrderFieldCreatedAt, OrderDirectionDesc)
+		if err != nil || got != want {
+			t.Errorf("githubSearchQuery(%q) = %q, %v, want %q", q, got, err, want)
+		}
+	}
+}
+
+func TestGitHubMergeStrategy(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	merged := created.Add(time.Hour)
+	newPullRequest := func(commits int, parents int, last, merge githubCommit) *githubPullRequest {
+		r := &githubPullRequest{State: ghv4.PullRequestStateMerged}
+		r.Commits.TotalCount = ghv4.Int(commits)
+		r.Commits.Nodes = append(r.Commits.Nodes, struct{ Commit githubCommit }{last})
+		r.MergeCommit.githubCommit = merge
+		r.MergeCommit.Parents.TotalCount = ghv4.Int(parents)
+		return r
+	}
+	last := githubCommit{Oid: "b", AuthoredDate: ghv4.DateTime{Time: created}, MessageHeadline: "Fix"}
+
+	tests := []struct {
+		name     string
+		pr       *githubPullRequest
+		strategy PullRequestMergeMethod
+		commits  int
+	}{
+		{
+			name:     "merge",
+			pr:       newPullRequest(3, 2, last, githubCommit{Oid: "m", AuthoredDate: ghv4.DateTime{Time: merged}, MessageHeadline: "Merge pull request #1"}),
+			strategy: PullRequestMergeMethodMerge,
+			commits:  3,
+		},
+		{
+			name:     "squash",
+			pr:       newPullRequest(3, 1, last, githubCommit{Oid: "s", AuthoredDate: ghv4.DateTime{Time: merged}, MessageHeadline: "Fix (#1)"}),
+			strategy: PullRequestMergeMethodSquash,
+			commits:  1,
+		},
+		{
+			name:     "rebase",
+			pr:       newPullRequest(3, 1, last, githubCommit{Oid: "r", AuthoredDate: ghv4.DateTime{Time: created}, MessageHeadline: "Fix"}),
+			strategy: PullRequestMergeMethodRebase,
+			commits:  3,
+		},
+		{
+			name:     "fast-forward",
+			pr:       newPullRequest(3, 1, last, last),
+			strategy: PullRequestMergeMethodRebase,
+			commits:  3,
+		},
+		{
+			name:     "single commit",
+			pr:       newPullRequest(1, 1, last, githubCommit{Oid: "s", AuthoredDate: ghv4.DateTime{Time: created}, MessageHeadline: "Fix"}),
+			strategy: PullRequestMergeMethodSquash,
+			commits:  1,
+		},
+		{
+			name:     "single commit merge",
+			pr:       newPullRequest(1, 2, last, githubCommit{Oid: "m", AuthoredDate: ghv4.DateTime{Time: merged}, MessageHeadline: "Merge pull request #1"}),
+			strategy: PullRequestMergeMethodMerge,
+			commits:  1,
+		},
+		{
+			name:    "open",
+			pr:      &githubPullRequest{State: ghv4.PullRequestStateOpen},
+			commits: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy, commits := tt.pr.mergeStrategy()
+			if strategy != tt.strategy || commits != tt.commits {
+				t.Errorf("mergeStrategy() = %s, %d, want %s, %d", strategy, commits, tt.strategy, tt.commits)
+			}
+		})
+	}
+}
+
+func TestContributionsWindow(t *testing.T) {
+	to := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	from, _, err := contributionsWindow(to.AddDate(-1, 0, 0).Add(-time.Hour), to)
+	if err != nil || !from.Equal(to.AddDate(-1, 0, 0)) {
+		t.Errorf("contributionsWindow() slightly exceeding = %s, %v, want clamped", from, err)
+	}
+	_, _, err = contributionsWindow(time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC), to)
+	if err == nil {
+		t.Errorf("contributionsWindow() exceeding = nil, want error")
+	}
+}
+
+func TestGitHubAsset(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		count++
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("asset request with the token")
+		}
+		rw.Write([]byte("\x89PNG\r\n\x1a\n"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	for _, policy := range []CachePolicy{{Dir: dir}, {Dir: dir}, {Dir: dir, Offline: true}} {
+		src, err := NewGitHub("", "token", policy, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, contentType, err := src.Asset(context.Background(), server.URL+"/u/1?v=4")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if contentType != "image/png" || len(data) != 8 {
+			t.Errorf("Asset() = %q, %q", data, contentType)
+		}
+	}
+	if count != 1 {
+		t.Errorf("requests = %d, want the asset cached", count)
+	}
+
+	src, err := NewGitHub("", "token", CachePolicy{Dir: dir, Offline: true}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = src.Asset(context.Background(), server.URL+"/u/2")
+	if err == nil || !strings.Contains(err.Error(), "offline") {
+		t.Errorf("Asset() offline error = %v", err)
+	}
+}
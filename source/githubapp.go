@@ -0,0 +1,138 @@
+package source
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	ghv3 "github.com/google/go-github/v66/github"
+	"golang.org/x/oauth2"
+)
+
+// gitHubAppTokenEarlyExpiry is how long before the expiry the installation token is refreshed
+const gitHubAppTokenEarlyExpiry = 5 * time.Minute
+
+// NewGitHubAppTokenSource returns the tokens of the installation of GitHub App, an empty baseURL selects github.com.
+// The token is exchanged with a JWT signed by the private key in PEM, and refreshed before it expires.
+func NewGitHubAppTokenSource(baseURL string, appID, installationID int64, privateKey []byte) (oauth2.TokenSource, error) {
+	key, err := parseRSAPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("github app %d: %w", appID, err)
+	}
+	jwt := &gitHubAppJWT{
+		appID: appID,
+		key:   key,
+		now:   time.Now,
+	}
+	cli := ghv3.NewClient(&http.Client{
+		Transport: jwt,
+	})
+	baseURL, err = gitHubEnterpriseURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if baseURL != "" {
+		cli, err = cli.WithEnterpriseURLs(baseURL+"/api/v3/", baseURL+"/api/uploads/")
+		if err != nil {
+			return nil, fmt.Errorf("base url %q: %w", baseURL, err)
+		}
+	}
+	src := &gitHubAppTokenSource{
+		installationID: installationID,
+		cli:            cli,
+	}
+	return oauth2.ReuseTokenSourceWithExpiry(nil, src, gitHubAppTokenEarlyExpiry), nil
+}
+
+// gitHubAppTokenSource exchanges the JWT of the app for the token of the installation
+type gitHubAppTokenSource struct {
+	installationID int64
+	cli            *ghv3.Client
+}
+
+func (s *gitHubAppTokenSource) Token() (*oauth2.Token, error) {
+	token, _, err := s.cli.Apps.CreateInstallationToken(context.Background(), s.installationID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("github app installation %d: %w", s.installationID, err)
+	}
+	return &oauth2.Token{
+		AccessToken: token.GetToken(),
+		TokenType:   "token",
+		Expiry:      token.GetExpiresAt().Time,
+	}, nil
+}
+
+// gitHubAppJWT authenticates the requests as the app
+type gitHubAppJWT struct {
+	appID int64
+	key   *rsa.PrivateKey
+	now   func() time.Time
+}
+
+func (j *gitHubAppJWT) RoundTrip(r *http.Request) (*http.Response, error) {
+	token, err := j.sign()
+	if err != nil {
+		return nil, err
+	}
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// sign returns the JWT signed with RS256, it's issued a minute ago to allow for the clock drift,
+// and expires in 9 minutes which is under the limit 10 minutes of GitHub.
+func (j *gitHubAppJWT) sign() (string, error) {
+	now := j.now()
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(j.appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, j.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("sign jwt: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// parseRSAPrivateKey parses the private key in PKCS #1 or PKCS #8 PEM
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("private key: no PEM")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("private key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key: not RSA")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,94 @@
+package source
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGitHubAppTokenSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+
+	count := 0
+	expiry := time.Now().Add(time.Hour)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v3/app/installations/2/access_tokens" {
+			t.Errorf("request %s %s", r.Method, r.URL.Path)
+		}
+		jwt := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+		if len(jwt) != 3 {
+			t.Fatalf("authorization %q", r.Header.Get("Authorization"))
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(jwt[2])
+		hash := sha256.Sum256([]byte(jwt[0] + "." + jwt[1]))
+		err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], sig)
+		if err != nil {
+			t.Errorf("verify jwt: %v", err)
+		}
+		claims, _ := base64.RawURLEncoding.DecodeString(jwt[1])
+		var c struct {
+			Iss string `json:"iss"`
+		}
+		json.Unmarshal(claims, &c)
+		if c.Iss != "1" {
+			t.Errorf("iss = %q, want 1", c.Iss)
+		}
+
+		count++
+		rw.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(rw, `{"token":"ghs_%d","expires_at":%q}`, count, expiry.Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	src, err := NewGitHubAppTokenSource(server.URL, 1, 2, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i != 2; i++ {
+		token, err := src.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.AccessToken != "ghs_1" {
+			t.Errorf("Token() = %q, want ghs_1", token.AccessToken)
+		}
+	}
+
+	// The token which expires soon is refreshed
+	expiry = time.Now().Add(time.Minute)
+	src, err = NewGitHubAppTokenSource(server.URL, 1, 2, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.Token()
+	token, err := src.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "ghs_3" {
+		t.Errorf("Token() = %q, want refreshed ghs_3", token.AccessToken)
+	}
+
+	_, err = NewGitHubAppTokenSource(server.URL, 1, 2, []byte("foo"))
+	if err == nil {
+		t.Errorf("NewGitHubAppTokenSource() with invalid key want error")
+	}
+}
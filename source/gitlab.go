@@ -0,0 +1,557 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const GitLabURL = "https://gitlab.com"
+
+var _ Source = (*GitLab)(nil)
+
+// GitLab is the source of GitLab, merge requests are listed as pull requests
+type GitLab struct {
+	cli *restClient
+}
+
+func NewGitLab(baseURL string, token string, cache CachePolicy, interval time.Duration, retry int) *GitLab {
+	if baseURL == "" {
+		baseURL = GitLabURL
+	}
+	return &GitLab{
+		cli: newRESTClient(strings.TrimSuffix(baseURL, "/")+"/api/v4", token, cache, interval, retry),
+	}
+}
+
+type gitlabUser struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+type gitlabProject struct {
+	PathWithNamespace string `json:"path_with_namespace"`
+	Archived          bool   `json:"archived"`
+	StarCount         int    `json:"star_count"`
+	ForksCount        int    `json:"forks_count"`
+	ForkedFromProject *struct {
+		ID int `json:"id"`
+	} `json:"forked_from_project"`
+}
+
+type gitlabEvent struct {
+	ActionName string `json:"action_name"`
+	TargetType string `json:"target_type"`
+	PushData   *struct {
+		CommitCount int `json:"commit_count"`
+	} `json:"push_data"`
+}
+
+type gitlabMergeRequest struct {
+	IID          int      `json:"iid"`
+	Title        string   `json:"title"`
+	State        string   `json:"state"`
+	TargetBranch string   `json:"target_branch"`
+	WebURL       string   `json:"web_url"`
+	Labels       []string `json:"labels"`
+	Squash       bool     `json:"squash"`
+	Author       struct {
+		Username string `json:"username"`
+	} `json:"author"`
+	References struct {
+		Full string `json:"full"`
+	} `json:"references"`
+	// MergeCommitSHA is empty if it's fast-forward merged
+	MergeCommitSHA string `json:"merge_commit_sha"`
+	Draft          bool   `json:"draft"`
+	UserNotesCount int    `json:"user_notes_count"`
+	MergeUser      struct {
+		Username string `json:"username"`
+	} `json:"merge_user"`
+	Reviewers []struct {
+		Username string `json:"username"`
+	} `json:"reviewers"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	MergedAt  time.Time `json:"merged_at"`
+	ClosedAt  time.Time `json:"closed_at"`
+}
+
+type gitlabIssue struct {
+	IID            int      `json:"iid"`
+	Title          string   `json:"title"`
+	State          string   `json:"state"`
+	WebURL         string   `json:"web_url"`
+	Labels         []string `json:"labels"`
+	UserNotesCount int      `json:"user_notes_count"`
+	Author         struct {
+		Username string `json:"username"`
+	} `json:"author"`
+	References struct {
+		Full string `json:"full"`
+	} `json:"references"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ClosedAt  time.Time `json:"closed_at"`
+}
+
+type gitlabDiff struct {
+	Diff string `json:"diff"`
+}
+
+func (s *GitLab) Stat(ctx context.Context, username string, from, to time.Time) (*Stat, error) {
+	ctx = withCacheClass(ctx, CacheStats)
+	user, err := s.user(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	repos, err := s.repositories(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	stat := Stat{}
+	stat.Name = user.Name
+	for _, repo := range repos {
+		stat.Stars += repo.Stars
+		if !repo.IsFork {
+			stat.Forks += repo.Forks
+		}
+	}
+
+	err = gitlabPages(ctx, s, fmt.Sprintf("/users/%d/contributed_projects", user.ID), url.Values{}, func(projects []gitlabProject) bool {
+		stat.ContributedTo += len(projects)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The events are filtered by dates exclusively
+	from, to = timeWindow(from, to)
+	query := url.Values{
+		"after":  []string{from.AddDate(0, 0, -1).Format(time.DateOnly)},
+		"before": []string{to.AddDate(0, 0, 1).Format(time.DateOnly)},
+	}
+	err = gitlabPages(ctx, s, fmt.Sprintf("/users/%d/events", user.ID), query, func(events []gitlabEvent) bool {
+		for _, event := range events {
+			switch {
+			case strings.HasPrefix(event.ActionName, "pushed"):
+				if event.PushData != nil {
+					stat.Commits += event.PushData.CommitCount
+				}
+			case event.ActionName == "opened" && event.TargetType == "MergeRequest":
+				stat.PullRequests++
+			case event.ActionName == "opened" && event.TargetType == "Issue":
+				stat.Issues++
+			case event.ActionName == "approved":
+				stat.Reviews++
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &stat, nil
+}
+
+func (s *GitLab) Repositories(ctx context.Context, username string) ([]*Repository, error) {
+	ctx = withCacheClass(ctx, CacheRepositories)
+	user, err := s.user(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return s.repositories(ctx, user.ID)
+}
+
+func (s *GitLab) repositories(ctx context.Context, userID int) ([]*Repository, error) {
+	repos := []*Repository{}
+	err := gitlabPages(ctx, s, fmt.Sprintf("/users/%d/projects", userID), url.Values{}, func(projects []gitlabProject) bool {
+		for _, project := range projects {
+			repos = append(repos, &Repository{
+				Name:       project.PathWithNamespace,
+				Stars:      project.StarCount,
+				Forks:      project.ForksCount,
+				IsFork:     project.ForkedFromProject != nil,
+				IsArchived: project.Archived,
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+func (s *GitLab) LifetimeStat(ctx context.Context, username string) (*Stat, error) {
+	return nil, fmt.Errorf("gitlab lifetime stat: %w", ErrNotSupported)
+}
+
+func (s *GitLab) OrgStat(ctx context.Context, username string, org string, from, to time.Time) (*OrgStat, error) {
+	return nil, fmt.Errorf("gitlab organization stat: %w", ErrNotSupported)
+}
+
+func (s *GitLab) PullRequests(ctx context.Context, username string, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...PullRequestCallback) ([]*PullRequest, error) {
+	ctx = withCacheClass(ctx, CachePullRequests)
+	var pageSize = MaxPageSize
+	if size > 0 && pageSize > size {
+		pageSize = size
+	}
+	if len(states) == 0 {
+		states = []PullRequestState{PullRequestStateOpen}
+	}
+
+	query, err := gitlabListQuery(username, orderField, orderDirection, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(states) == 1 {
+		query.Set("state", gitlabState(states[0]))
+	}
+	return s.mergeRequests(ctx, "/merge_requests", query, states, size, cbs)
+}
+
+// ScopedPullRequests lists the merge requests of the project or the group
+func (s *GitLab) ScopedPullRequests(ctx context.Context, scope Scope, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...PullRequestCallback) ([]*PullRequest, error) {
+	ctx = withCacheClass(ctx, CachePullRequests)
+	var pageSize = MaxPageSize
+	if size > 0 && pageSize > size {
+		pageSize = size
+	}
+	if len(states) == 0 {
+		states = []PullRequestState{PullRequestStateOpen}
+	}
+
+	var path string
+	switch scope.Kind {
+	case ScopeRepository:
+		path = "/projects/" + url.PathEscape(scope.Name) + "/merge_requests"
+	case ScopeOrg:
+		path = "/groups/" + url.PathEscape(scope.Name) + "/merge_requests"
+	default:
+		return nil, fmt.Errorf("gitlab scope %s: %w", scope, ErrNotSupported)
+	}
+	query, err := gitlabListQuery("", orderField, orderDirection, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(states) == 1 {
+		query.Set("state", gitlabState(states[0]))
+	}
+	return s.mergeRequests(ctx, path, query, states, size, cbs)
+}
+
+// mergeRequests lists the merge requests of the path
+func (s *GitLab) mergeRequests(ctx context.Context, path string, query url.Values, states []PullRequestState, size int, cbs []PullRequestCallback) ([]*PullRequest, error) {
+	var err error
+	prs := []*PullRequest{}
+	pageErr := gitlabPages(ctx, s, path, query, func(mrs []gitlabMergeRequest) bool {
+		for _, mr := range mrs {
+			if size >= 0 && len(prs) >= size {
+				return false
+			}
+			var pr *PullRequest
+			pr, err = s.conv(ctx, &mr, states)
+			if err != nil {
+				return false
+			}
+			if pr == nil {
+				continue
+			}
+			for _, cb := range cbs {
+				if !cb(pr) {
+					return false
+				}
+			}
+			prs = append(prs, pr)
+		}
+		return size < 0 || len(prs) < size
+	})
+	if pageErr != nil {
+		return nil, pageErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return prs, nil
+}
+
+// conv converts the merge request, it returns nil if the state is not listed.
+// The details are fetched for each merge request unless they're deferred by the context.
+func (s *GitLab) conv(ctx context.Context, mr *gitlabMergeRequest, states []PullRequestState) (*PullRequest, error) {
+	state := PullRequestStateOpen
+	switch mr.State {
+	case "merged":
+		state = PullRequestStateMerged
+	case "closed":
+		state = PullRequestStateClosed
+	}
+	listed := false
+	for _, s := range states {
+		if s == state {
+			listed = true
+			break
+		}
+	}
+	if !listed {
+		return nil, nil
+	}
+
+	u, err := url.Parse(mr.WebURL)
+	if err != nil {
+		return nil, err
+	}
+	p := PullRequest{
+		Username:   mr.Author.Username,
+		Repository: strings.SplitN(mr.References.Full, "!", 2)[0],
+		Number:     mr.IID,
+		Title:      mr.Title,
+		URL:        u,
+		BaseRef:    mr.TargetBranch,
+		State:      string(state),
+		CreatedAt:  mr.CreatedAt,
+		ClosedAt:   mr.ClosedAt,
+		MergedAt:   mr.MergedAt,
+		UpdatedAt:  mr.UpdatedAt,
+		SortTime:   mr.UpdatedAt,
+		IsDraft:    mr.Draft,
+		Comments:   mr.UserNotesCount,
+		MergedBy:   mr.MergeUser.Username,
+	}
+	if len(mr.Labels) != 0 {
+		p.Labels = mr.Labels
+	}
+	for _, reviewer := range mr.Reviewers {
+		p.RequestedReviewers = append(p.RequestedReviewers, reviewer.Username)
+	}
+
+	if state == PullRequestStateMerged {
+		switch {
+		case mr.Squash:
+			p.MergeStrategy = string(PullRequestMergeMethodSquash)
+		case mr.MergeCommitSHA != "":
+			p.MergeStrategy = string(PullRequestMergeMethodMerge)
+		default:
+			p.MergeStrategy = string(PullRequestMergeMethodRebase)
+		}
+	}
+	if !pullRequestDetailsDeferred(ctx) {
+		err = s.pullRequestDetails(ctx, &p)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	setSortTime(&p, states)
+	return &p, nil
+}
+
+// pullRequestDetails fetches the changes and the commits of the merge request, which aren't in the listings
+func (s *GitLab) pullRequestDetails(ctx context.Context, pr *PullRequest) error {
+	path := fmt.Sprintf("/projects/%s/merge_requests/%d", url.PathEscape(pr.Repository), pr.Number)
+	pr.Additions, pr.Deletions, pr.ChangedFiles = 0, 0, 0
+	err := gitlabPages(ctx, s, path+"/diffs", url.Values{}, func(diffs []gitlabDiff) bool {
+		for _, diff := range diffs {
+			pr.ChangedFiles++
+			// The diff has only the hunks without the file headers, so all the lines with the prefixes are changes
+			for _, line := range strings.Split(diff.Diff, "\n") {
+				switch {
+				case strings.HasPrefix(line, "+"):
+					pr.Additions++
+				case strings.HasPrefix(line, "-"):
+					pr.Deletions++
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	pr.ChangeSize = changeSize(pr.Additions + pr.Deletions)
+
+	pr.Commits = 0
+	if pr.MergeStrategy == string(PullRequestMergeMethodSquash) {
+		pr.Commits = 1
+		return nil
+	}
+	return gitlabPages(ctx, s, path+"/commits", url.Values{}, func(commits []struct{}) bool {
+		pr.Commits += len(commits)
+		return true
+	})
+}
+
+func (s *GitLab) Issues(ctx context.Context, username string, states []IssueState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...IssueCallback) ([]*Issue, error) {
+	ctx = withCacheClass(ctx, CacheIssues)
+	var pageSize = MaxPageSize
+	if size > 0 && pageSize > size {
+		pageSize = size
+	}
+	if len(states) == 0 {
+		states = []IssueState{IssueStateOpen}
+	}
+
+	query, err := gitlabListQuery(username, orderField, orderDirection, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(states) == 1 {
+		query.Set("state", gitlabIssueState(states[0]))
+	}
+
+	issues := []*Issue{}
+	pageErr := gitlabPages(ctx, s, "/issues", query, func(items []gitlabIssue) bool {
+		for _, item := range items {
+			if size >= 0 && len(issues) >= size {
+				return false
+			}
+			var issue *Issue
+			issue, err = s.convIssue(&item, states)
+			if err != nil {
+				return false
+			}
+			if issue == nil {
+				continue
+			}
+			for _, cb := range cbs {
+				if !cb(issue) {
+					return false
+				}
+			}
+			issues = append(issues, issue)
+		}
+		return size < 0 || len(issues) < size
+	})
+	if pageErr != nil {
+		return nil, pageErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// convIssue converts the issue, it returns nil if the state is not listed
+func (s *GitLab) convIssue(item *gitlabIssue, states []IssueState) (*Issue, error) {
+	state := IssueStateOpen
+	if item.State == "closed" {
+		state = IssueStateClosed
+	}
+	listed := false
+	for _, s := range states {
+		if s == state {
+			listed = true
+			break
+		}
+	}
+	if !listed {
+		return nil, nil
+	}
+
+	u, err := url.Parse(item.WebURL)
+	if err != nil {
+		return nil, err
+	}
+	i := Issue{
+		Username:   item.Author.Username,
+		Repository: strings.SplitN(item.References.Full, "#", 2)[0],
+		Number:     item.IID,
+		Title:      item.Title,
+		URL:        u,
+		State:      string(state),
+		Comments:   item.UserNotesCount,
+		CreatedAt:  item.CreatedAt,
+		ClosedAt:   item.ClosedAt,
+		UpdatedAt:  item.UpdatedAt,
+		SortTime:   item.UpdatedAt,
+	}
+	if len(item.Labels) != 0 {
+		i.Labels = item.Labels
+	}
+	setIssueSortTime(&i, states)
+	return &i, nil
+}
+
+func (s *GitLab) user(ctx context.Context, username string) (*gitlabUser, error) {
+	var users []gitlabUser
+	_, err := s.cli.get(ctx, "/users", url.Values{"username": []string{username}}, &users)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, fmt.Errorf("user %q not found", username)
+	}
+	return &users[0], nil
+}
+
+// gitlabPages lists all pages of path until next returns false
+func gitlabPages[T any](ctx context.Context, s *GitLab, path string, query url.Values, next func(items []T) bool) error {
+	if query.Get("per_page") == "" {
+		query.Set("per_page", strconv.Itoa(MaxPageSize))
+	}
+	page := "1"
+	for page != "" {
+		query.Set("page", page)
+		var items []T
+		header, err := s.cli.get(ctx, path, query, &items)
+		if err != nil {
+			return err
+		}
+		if !next(items) {
+			return nil
+		}
+		page = header.Get("X-Next-Page")
+	}
+	return nil
+}
+
+// gitlabListQuery is the query to list the merge requests or issues authored by username, or of all the authors if it's empty
+func gitlabListQuery(username string, orderField IssueOrderField, orderDirection OrderDirection, pageSize int) (url.Values, error) {
+	query := url.Values{
+		"per_page": []string{strconv.Itoa(pageSize)},
+		"state":    []string{"all"},
+	}
+	if username != "" {
+		query.Set("scope", "all")
+		query.Set("author_username", username)
+	}
+	switch orderField {
+	case IssueOrderFieldCreatedAt:
+		query.Set("order_by", "created_at")
+	case IssueOrderFieldUpdatedAt:
+		query.Set("order_by", "updated_at")
+	default:
+		return nil, fmt.Errorf("gitlab order by %s: %w", orderField, ErrNotSupported)
+	}
+	query.Set("sort", strings.ToLower(string(orderDirection)))
+	return query, nil
+}
+
+func gitlabIssueState(state IssueState) string {
+	if state == IssueStateClosed {
+		return "closed"
+	}
+	return "opened"
+}
+
+func gitlabState(state PullRequestState) string {
+	switch state {
+	case PullRequestStateMerged:
+		return "merged"
+	case PullRequestStateClosed:
+		return "closed"
+	default:
+		return "opened"
+	}
+}
+
+func (s *GitLab) Reviews(ctx context.Context, username string, from, to time.Time, size int, cbs ...ReviewCallback) ([]*Review, error) {
+	return nil, fmt.Errorf("gitlab reviews: %w", ErrNotSupported)
+}
@@ -0,0 +1,259 @@
+package source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newGitLabServer(t *testing.T) *httptest.Server {
+	return newGitLabServerCount(t, map[string]int{})
+}
+
+// newGitLabServerCount counts the requests of each path
+func newGitLabServerCount(t *testing.T, count map[string]int) *httptest.Server {
+	routes := map[string]string{
+		"/api/v4/users": `[{"id":1,"username":"foo","name":"Foo"}]`,
+		"/api/v4/users/1/projects": `[
+			{"star_count":10,"forks_count":2},
+			{"star_count":3,"forks_count":5,"forked_from_project":{"id":9}}
+		]`,
+		"/api/v4/users/1/contributed_projects": `[{},{},{}]`,
+		"/api/v4/users/1/events": `[
+			{"action_name":"pushed to","push_data":{"commit_count":4}},
+			{"action_name":"pushed new","push_data":{"commit_count":1}},
+			{"action_name":"opened","target_type":"MergeRequest"},
+			{"action_name":"opened","target_type":"Issue"},
+			{"action_name":"approved","target_type":"MergeRequest"}
+		]`,
+		"/api/v4/merge_requests": `[
+			{
+				"iid":5,"project_id":7,"title":"Add feature","state":"merged","target_branch":"main",
+				"web_url":"https://gitlab.example.com/group/project/-/merge_requests/5",
+				"labels":["feature"],"squash":false,"author":{"username":"foo"},
+				"references":{"full":"group/project!5"},"merge_commit_sha":"8f2d1c0",
+				"created_at":"2024-01-02T03:04:05.000Z","updated_at":"2024-01-04T03:04:05.000Z",
+				"merged_at":"2024-01-03T03:04:05.000Z","closed_at":null
+			},
+			{
+				"iid":6,"project_id":7,"title":"Squashed","state":"merged","target_branch":"main",
+				"web_url":"https://gitlab.example.com/group/project/-/merge_requests/6",
+				"squash":true,"author":{"username":"foo"},
+				"references":{"full":"group/project!6"},
+				"created_at":"2024-01-01T03:04:05.000Z","updated_at":"2024-01-01T03:04:05.000Z",
+				"merged_at":"2024-01-01T03:04:05.000Z"
+			},
+			{
+				"iid":7,"project_id":7,"title":"Draft","state":"opened","target_branch":"main",
+				"web_url":"https://gitlab.example.com/group/project/-/merge_requests/7",
+				"author":{"username":"foo"},
+				"references":{"full":"group/project!7"},
+				"created_at":"2023-12-01T03:04:05.000Z","updated_at":"2023-12-01T03:04:05.000Z"
+			}
+		]`,
+		"/api/v4/projects/group/project/merge_requests/5/diffs": `[
+			{"diff":"@@ -1,2 +1,3 @@\n a\n-b\n+c\n+d\n"},
+			{"diff":"@@ -0,0 +1 @@\n+e\n"}
+		]`,
+		"/api/v4/projects/group/project/merge_requests/5/commits": `[{},{},{}]`,
+		"/api/v4/projects/group/project/merge_requests/6/diffs":   `[{"diff":"@@ -1,2 +1,2 @@\n-a\n---y\n+b\n+++x\n\\ No newline at end of file\n"}]`,
+		"/api/v4/projects/group/project/merge_requests/7/diffs":   `[]`,
+		"/api/v4/projects/group/project/merge_requests/7/commits": `[{}]`,
+	}
+	var mut sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		count[r.URL.Path]++
+		mut.Unlock()
+		body, ok := routes[r.URL.Path]
+		if !ok {
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(rw, r)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(body))
+	}))
+}
+
+func TestGitLabStat(t *testing.T) {
+	server := newGitLabServer(t)
+	defer server.Close()
+
+	src := NewGitLab(server.URL, "", CachePolicy{}, 0, 0)
+	stat, err := src.Stat(context.Background(), "foo", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Stat{
+		Name:          "Foo",
+		Stars:         13,
+		Forks:         2,
+		Issues:        1,
+		Commits:       5,
+		Reviews:       1,
+		PullRequests:  1,
+		ContributedTo: 3,
+	}
+	if *stat != want {
+		t.Errorf("Stat() = %+v, want %+v", *stat, want)
+	}
+}
+
+func TestGitLabPullRequests(t *testing.T) {
+	server := newGitLabServer(t)
+	defer server.Close()
+
+	src := NewGitLab(server.URL, "", CachePolicy{}, 0, 0)
+	prs, err := src.PullRequests(context.Background(), "foo",
+		[]PullRequestState{PullRequestStateMerged},
+		IssueOrderFieldCreatedAt, OrderDirectionDesc, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 2 {
+		t.Fatalf("PullRequests() got %d, want 2", len(prs))
+	}
+
+	pr := prs[0]
+	if pr.Repository != "group/project" || pr.Number != 5 || pr.BaseRef != "main" {
+		t.Errorf("PullRequests() = %+v", pr)
+	}
+	if pr.State != string(PullRequestStateMerged) || !pr.SortTime.Equal(pr.MergedAt) {
+		t.Errorf("PullRequests() state = %s, sort time = %s", pr.State, pr.SortTime)
+	}
+	if pr.Additions != 3 || pr.Deletions != 1 || pr.ChangedFiles != 2 || pr.Commits != 3 {
+		t.Errorf("PullRequests() change = +%d -%d files %d commits %d", pr.Additions, pr.Deletions, pr.ChangedFiles, pr.Commits)
+	}
+	if len(pr.Labels) != 1 || pr.Labels[0] != "feature" {
+		t.Errorf("PullRequests() labels = %v", pr.Labels)
+	}
+	if prs[1].Commits != 1 {
+		t.Errorf("PullRequests() squashed commits = %d, want 1", prs[1].Commits)
+	}
+	// The lines like "+++x" and "---y" are the changes of the hunks
+	if prs[1].Additions != 2 || prs[1].Deletions != 2 {
+		t.Errorf("PullRequests() squashed change = +%d -%d, want +2 -2", prs[1].Additions, prs[1].Deletions)
+	}
+	if prs[0].MergeStrategy != string(PullRequestMergeMethodMerge) || prs[1].MergeStrategy != string(PullRequestMergeMethodSquash) {
+		t.Errorf("PullRequests() merge strategies = %s, %s", prs[0].MergeStrategy, prs[1].MergeStrategy)
+	}
+
+	prs, err = src.PullRequests(context.Background(), "foo",
+		[]PullRequestState{PullRequestStateMerged},
+		IssueOrderFieldCreatedAt, OrderDirectionDesc, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 1 {
+		t.Errorf("PullRequests() with size got %d, want 1", len(prs))
+	}
+}
+
+func TestGitLabMemoPullRequests(t *testing.T) {
+	count := map[string]int{}
+	server := newGitLabServerCount(t, count)
+	defer server.Close()
+
+	src := NewMemo(NewGitLab(server.URL, "", CachePolicy{}, 0, 0))
+	for i := 0; i != 2; i++ {
+		prs, err := src.PullRequests(context.Background(), "foo",
+			[]PullRequestState{PullRequestStateOpen},
+			IssueOrderFieldCreatedAt, OrderDirectionDesc, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(prs) != 1 || prs[0].Number != 7 || prs[0].Commits != 1 {
+			t.Fatalf("PullRequests() = %+v", prs)
+		}
+	}
+	// Only the details of the listed merge request are fetched, once
+	for path, n := range count {
+		if strings.Contains(path, "/merge_requests/") && (n != 1 || !strings.Contains(path, "/merge_requests/7/")) {
+			t.Errorf("requests of %s = %d", path, n)
+		}
+	}
+	if count["/api/v4/projects/group/project/merge_requests/7/commits"] != 1 {
+		t.Errorf("requests = %v, want the commits of !7", count)
+	}
+
+	prs, err := src.PullRequests(context.Background(), "foo",
+		[]PullRequestState{PullRequestStateMerged},
+		IssueOrderFieldCreatedAt, OrderDirectionDesc, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 1 || prs[0].Additions != 3 || prs[0].Commits != 3 {
+		t.Errorf("PullRequests() = %+v", prs)
+	}
+	if count["/api/v4/projects/group/project/merge_requests/6/diffs"] != 0 {
+		t.Errorf("requests = %v, want no details beyond the size", count)
+	}
+	if count["/api/v4/merge_requests"] != 1 {
+		t.Errorf("requests = %v, want the merge requests listed once", count)
+	}
+}
+
+func TestGitLabPageSize(t *testing.T) {
+	var perPage []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		perPage = append(perPage, r.URL.Query().Get("per_page"))
+		rw.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	src := NewGitLab(server.URL, "", CachePolicy{}, 0, 0)
+	for _, size := range []int{0, 5} {
+		_, err := src.PullRequests(context.Background(), "foo", nil, IssueOrderFieldCreatedAt, OrderDirectionDesc, size)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{strconv.Itoa(MaxPageSize), "5"}
+	if strings.Join(perPage, ",") != strings.Join(want, ",") {
+		t.Errorf("per_page = %v, want %v", perPage, want)
+	}
+}
+
+func TestGitLabRepositories(t *testing.T) {
+	var pages []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v4/users":
+			rw.Write([]byte(`[{"id":1,"username":"foo","name":"Foo"}]`))
+		case "/api/v4/users/1/projects":
+			page := r.URL.Query().Get("page")
+			pages = append(pages, page)
+			if page == "1" {
+				rw.Header().Set("X-Next-Page", "2")
+				rw.Write([]byte(`[{"path_with_namespace":"foo/a","star_count":1},{"path_with_namespace":"foo/b","star_count":2}]`))
+				return
+			}
+			rw.Header().Set("X-Next-Page", "")
+			rw.Write([]byte(`[{"path_with_namespace":"foo/c","star_count":3}]`))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	repos, err := NewGitLab(server.URL, "", CachePolicy{}, 0, 0).Repositories(context.Background(), "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, repo := range repos {
+		names = append(names, repo.Name)
+	}
+	if strings.Join(names, ",") != "foo/a,foo/b,foo/c" {
+		t.Errorf("Repositories() = %v", names)
+	}
+	if strings.Join(pages, ",") != "1,2" {
+		t.Errorf("pages = %v, want 1,2", pages)
+	}
+}
@@ -0,0 +1,393 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	allPullRequestStates = []PullRequestState{PullRequestStateOpen, PullRequestStateClosed, PullRequestStateMerged}
+	allIssueStates       = []IssueState{IssueStateOpen, IssueStateClosed}
+)
+
+// Memo memoizes the queries of the source in a run, the concurrent identical queries are fetched once.
+//
+// The pull requests and issues of a user are fetched in all states ordered by creation time, newest first,
+// and then each listing is served from them, the history is only fetched again when a listing needs
+// older items than those already fetched. The callbacks of the listings must stop at a point of the order.
+type Memo struct {
+	src   Source
+	group singleflight.Group
+
+	// fetchSize is the least number of the items fetched to extend a history
+	fetchSize int
+
+	mut    sync.Mutex
+	values map[string]interface{}
+	prs    map[string]*memoList[PullRequest]
+	issues map[string]*memoList[Issue]
+}
+
+// memoList is the newest part of a history
+type memoList[T any] struct {
+	items []*T
+	// complete means the whole history is fetched
+	complete bool
+}
+
+// NewMemo returns the memoized source, the CommitLister of the source is kept
+func NewMemo(src Source) Source {
+	m := &Memo{
+		src:       src,
+		fetchSize: MaxPageSize,
+		values:    map[string]interface{}{},
+		prs:       map[string]*memoList[PullRequest]{},
+		issues:    map[string]*memoList[Issue]{},
+	}
+	if lister, ok := src.(CommitLister); ok {
+		return &memoCommitLister{
+			Memo:         m,
+			CommitLister: lister,
+		}
+	}
+	return m
+}
+
+var (
+	_ PullRequestIterator = (*Memo)(nil)
+	_ AssetFetcher        = (*Memo)(nil)
+)
+
+// pullRequestDetailer is the source which fetches some fields of each pull request separately,
+// they're fetched by Memo only for the listed pull requests.
+type pullRequestDetailer interface {
+	pullRequestDetails(ctx context.Context, pr *PullRequest) error
+}
+
+type deferDetailsKey struct{}
+
+// withoutPullRequestDetails defers the details of the pull requests listed with the context
+func withoutPullRequestDetails(ctx context.Context) context.Context {
+	return context.WithValue(ctx, deferDetailsKey{}, true)
+}
+
+// pullRequestDetailsDeferred reports whether the details of the pull requests listed with the context are deferred
+func pullRequestDetailsDeferred(ctx context.Context) bool {
+	deferred, _ := ctx.Value(deferDetailsKey{}).(bool)
+	return deferred
+}
+
+type memoCommitLister struct {
+	*Memo
+	CommitLister
+}
+
+// do returns the memoized value of the key, or fetches it once.
+// The fetch is shared by the concurrent callers, so it isn't canceled with a caller, the caller only stops waiting.
+func do[T any](ctx context.Context, m *Memo, key string, fetch func(ctx context.Context) (T, error)) (T, error) {
+	m.mut.Lock()
+	v, ok := m.values[key]
+	m.mut.Unlock()
+	if ok {
+		return v.(T), nil
+	}
+
+	ch := m.group.DoChan(key, func() (interface{}, error) {
+		v, err := fetch(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		m.mut.Lock()
+		m.values[key] = v
+		m.mut.Unlock()
+		return v, nil
+	})
+	var zero T
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case r := <-ch:
+		if r.Err != nil {
+			return zero, r.Err
+		}
+		return r.Val.(T), nil
+	}
+}
+
+// Asset fetches the asset through the source, which caches it
+func (m *Memo) Asset(ctx context.Context, uri string) ([]byte, string, error) {
+	fetcher, ok := m.src.(AssetFetcher)
+	if !ok {
+		return nil, "", fmt.Errorf("asset %s: %w", uri, ErrNotSupported)
+	}
+	return fetcher.Asset(ctx, uri)
+}
+
+func (m *Memo) Stat(ctx context.Context, username string, from, to time.Time) (*Stat, error) {
+	key := fmt.Sprintf("stat %s %d %d", username, from.Unix(), to.Unix())
+	stat, err := do(ctx, m, key, func(ctx context.Context) (*Stat, error) {
+		return m.src.Stat(ctx, username, from, to)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s := *stat
+	return &s, nil
+}
+
+func (m *Memo) LifetimeStat(ctx context.Context, username string) (*Stat, error) {
+	stat, err := do(ctx, m, "lifetime "+username, func(ctx context.Context) (*Stat, error) {
+		return m.src.LifetimeStat(ctx, username)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s := *stat
+	return &s, nil
+}
+
+func (m *Memo) Repositories(ctx context.Context, username string) ([]*Repository, error) {
+	repos, err := do(ctx, m, "repositories "+username, func(ctx context.Context) ([]*Repository, error) {
+		return m.src.Repositories(ctx, username)
+	})
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Repository, 0, len(repos))
+	for _, repo := range repos {
+		r := *repo
+		list = append(list, &r)
+	}
+	return list, nil
+}
+
+func (m *Memo) OrgStat(ctx context.Context, username string, org string, from, to time.Time) (*OrgStat, error) {
+	key := fmt.Sprintf("org %s %s %d %d", username, org, from.Unix(), to.Unix())
+	stat, err := do(ctx, m, key, func(ctx context.Context) (*OrgStat, error) {
+		return m.src.OrgStat(ctx, username, org, from, to)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s := *stat
+	return &s, nil
+}
+
+func (m *Memo) Reviews(ctx context.Context, username string, from, to time.Time, size int, cbs ...ReviewCallback) ([]*Review, error) {
+	if len(cbs) != 0 {
+		return m.src.Reviews(ctx, username, from, to, size, cbs...)
+	}
+	key := fmt.Sprintf("reviews %s %d %d %d", username, from.Unix(), to.Unix(), size)
+	reviews, err := do(ctx, m, key, func(ctx context.Context) ([]*Review, error) {
+		return m.src.Reviews(ctx, username, from, to, size)
+	})
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Review, 0, len(reviews))
+	for _, review := range reviews {
+		r := *review
+		list = append(list, &r)
+	}
+	return list, nil
+}
+
+func (m *Memo) PullRequests(ctx context.Context, username string, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...PullRequestCallback) ([]*PullRequest, error) {
+	if orderField != IssueOrderFieldCreatedAt || orderDirection != OrderDirectionDesc {
+		return m.src.PullRequests(ctx, username, states, orderField, orderDirection, size, cbs...)
+	}
+	listed, conv := pullRequestListing(states)
+	return memoListing(ctx, m, m.prs, "prs "+username, listed, conv, m.pullRequestDetails(), size, cbs,
+		func(ctx context.Context, stop PullRequestCallback) ([]*PullRequest, error) {
+			return m.src.PullRequests(withoutPullRequestDetails(ctx), username, allPullRequestStates, orderField, orderDirection, -1, stop)
+		})
+}
+
+// IterPullRequests iterates the memoized pull requests of the user, the history is fetched as far as the iteration goes
+func (m *Memo) IterPullRequests(ctx context.Context, username string, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection) iter.Seq2[*PullRequest, error] {
+	if orderField != IssueOrderFieldCreatedAt || orderDirection != OrderDirectionDesc {
+		return IterPullRequests(ctx, m.src, username, states, orderField, orderDirection)
+	}
+	return iterPullRequests(func(cb PullRequestCallback) error {
+		_, err := m.PullRequests(ctx, username, states, orderField, orderDirection, -1, cb)
+		return err
+	})
+}
+
+func (m *Memo) ScopedPullRequests(ctx context.Context, scope Scope, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...PullRequestCallback) ([]*PullRequest, error) {
+	if orderField != IssueOrderFieldCreatedAt || orderDirection != OrderDirectionDesc {
+		return m.src.ScopedPullRequests(ctx, scope, states, orderField, orderDirection, size, cbs...)
+	}
+	listed, conv := pullRequestListing(states)
+	return memoListing(ctx, m, m.prs, "scoped prs "+scope.String(), listed, conv, m.pullRequestDetails(), size, cbs,
+		func(ctx context.Context, stop PullRequestCallback) ([]*PullRequest, error) {
+			return m.src.ScopedPullRequests(withoutPullRequestDetails(ctx), scope, allPullRequestStates, orderField, orderDirection, -1, stop)
+		})
+}
+
+func (m *Memo) Issues(ctx context.Context, username string, states []IssueState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...IssueCallback) ([]*Issue, error) {
+	if orderField != IssueOrderFieldCreatedAt || orderDirection != OrderDirectionDesc {
+		return m.src.Issues(ctx, username, states, orderField, orderDirection, size, cbs...)
+	}
+	if len(states) == 0 {
+		states = []IssueState{IssueStateOpen}
+	}
+	listed := func(issue *Issue) bool {
+		for _, state := range states {
+			if string(state) == issue.State {
+				return true
+			}
+		}
+		return false
+	}
+	conv := func(issue *Issue) *Issue {
+		i := *issue
+		i.SortTime = i.UpdatedAt
+		setIssueSortTime(&i, states)
+		return &i
+	}
+	return memoListing(ctx, m, m.issues, "issues "+username, listed, conv, nil, size, cbs,
+		func(ctx context.Context, stop IssueCallback) ([]*Issue, error) {
+			return m.src.Issues(ctx, username, allIssueStates, orderField, orderDirection, -1, stop)
+		})
+}
+
+// pullRequestDetails returns the pull request with the details fetched once,
+// it's nil if the source lists the pull requests with the details.
+func (m *Memo) pullRequestDetails() func(ctx context.Context, pr *PullRequest) (*PullRequest, error) {
+	detailer, ok := m.src.(pullRequestDetailer)
+	if !ok {
+		return nil
+	}
+	return func(ctx context.Context, pr *PullRequest) (*PullRequest, error) {
+		key := fmt.Sprintf("pr details %s#%d", pr.Repository, pr.Number)
+		return do(ctx, m, key, func(ctx context.Context) (*PullRequest, error) {
+			p := *pr
+			err := detailer.pullRequestDetails(ctx, &p)
+			if err != nil {
+				return nil, err
+			}
+			return &p, nil
+		})
+	}
+}
+
+// pullRequestListing returns whether the pull request is listed in the states, and the copy of it listed
+func pullRequestListing(states []PullRequestState) (func(*PullRequest) bool, func(*PullRequest) *PullRequest) {
+	if len(states) == 0 {
+		states = []PullRequestState{PullRequestStateOpen}
+	}
+	listed := func(pr *PullRequest) bool {
+		for _, state := range states {
+			if string(state) == pr.State {
+				return true
+			}
+		}
+		return false
+	}
+	conv := func(pr *PullRequest) *PullRequest {
+		p := *pr
+		p.SortTime = p.UpdatedAt
+		setSortTime(&p, states)
+		return &p
+	}
+	return listed, conv
+}
+
+// memoListing serves the listing from the memoized history of the key, the history is fetched
+// further when it's not enough for the listing.
+// The callbacks are called once for each listed item in order with the copy of it, the details of which
+// are fetched by detail if it's not nil, so the history is fetched without them.
+// The items are served on the goroutine of the listing, so the callbacks don't run in the shared fetch.
+func memoListing[T any, C ~func(*T) bool](ctx context.Context, m *Memo, lists map[string]*memoList[T], key string, listed func(*T) bool, conv func(*T) *T, detail func(ctx context.Context, item *T) (*T, error), size int, cbs []C, fetch func(ctx context.Context, stop C) ([]*T, error)) ([]*T, error) {
+	items := []*T{}
+	if size == 0 {
+		return items, nil
+	}
+
+	// pos is the position in the history before which the items are served
+	pos := 0
+	for {
+		m.mut.Lock()
+		list, ok := lists[key]
+		m.mut.Unlock()
+		if ok {
+			for ; pos < len(list.items); pos++ {
+				item := list.items[pos]
+				if !listed(item) {
+					continue
+				}
+				if detail != nil {
+					var err error
+					item, err = detail(ctx, item)
+					if err != nil {
+						return nil, err
+					}
+				}
+				c := conv(item)
+				for _, cb := range cbs {
+					if !cb(c) {
+						return items, nil
+					}
+				}
+				items = append(items, c)
+				if size >= 0 && len(items) >= size {
+					return items, nil
+				}
+			}
+			if list.complete {
+				return items, nil
+			}
+		}
+
+		err := memoFetch(ctx, m, lists, key, fetch)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// memoFetch fetches the history of the key twice as long as the memoized one, or a page at least.
+// The fetch is shared by the concurrent listings of the key, so it isn't canceled with a listing, the listing only stops waiting.
+func memoFetch[T any, C ~func(*T) bool](ctx context.Context, m *Memo, lists map[string]*memoList[T], key string, fetch func(ctx context.Context, stop C) ([]*T, error)) error {
+	ch := m.group.DoChan(key, func() (interface{}, error) {
+		m.mut.Lock()
+		want := m.fetchSize
+		if list, ok := lists[key]; ok && 2*len(list.items) > want {
+			want = 2 * len(list.items)
+		}
+		m.mut.Unlock()
+
+		i := 0
+		stop := C(func(item *T) bool {
+			i++
+			return i <= want
+		})
+		history, err := fetch(context.WithoutCancel(ctx), stop)
+		if err != nil {
+			return nil, err
+		}
+		complete := i <= want
+		m.mut.Lock()
+		// Both are the newest part of the history, so the longer one is kept
+		if old, ok := lists[key]; !ok || complete || len(history) > len(old.items) {
+			lists[key] = &memoList[T]{
+				items:    history,
+				complete: complete,
+			}
+		}
+		m.mut.Unlock()
+		return nil, nil
+	})
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case r := <-ch:
+		return r.Err
+	}
+}
@@ -0,0 +1,273 @@
+package source
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type countSource struct {
+	Git
+	prs   []*PullRequest
+	count int
+}
+
+func (s *countSource) PullRequests(ctx context.Context, username string, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...PullRequestCallback) ([]*PullRequest, error) {
+	s.count++
+	prs := []*PullRequest{}
+	for _, pr := range s.prs {
+		listed := false
+		for _, state := range states {
+			if string(state) == pr.State {
+				listed = true
+			}
+		}
+		if !listed {
+			continue
+		}
+		for _, cb := range cbs {
+			if !cb(pr) {
+				return prs, nil
+			}
+		}
+		p := *pr
+		prs = append(prs, &p)
+		if size >= 0 && len(prs) >= size {
+			break
+		}
+	}
+	return prs, nil
+}
+
+func TestMemoPullRequests(t *testing.T) {
+	now := time.Now()
+	src := &countSource{}
+	for i := 0; i != 10; i++ {
+		state := PullRequestStateMerged
+		if i%2 == 1 {
+			state = PullRequestStateOpen
+		}
+		src.prs = append(src.prs, &PullRequest{
+			Number:    i,
+			State:     string(state),
+			CreatedAt: now.AddDate(0, 0, -10*i),
+			UpdatedAt: now.AddDate(0, 0, -10*i+1),
+			MergedAt:  now.AddDate(0, 0, -10*i+2),
+		})
+	}
+	since := func(days int) PullRequestCallback {
+		return func(pr *PullRequest) bool {
+			return pr.CreatedAt.After(now.AddDate(0, 0, -days))
+		}
+	}
+
+	memo := NewMemo(src)
+	memo.(*memoCommitLister).fetchSize = 4
+	ctx := context.Background()
+	// The first 4 pull requests are not enough, so the history is extended to 8
+	prs, err := memo.PullRequests(ctx, "foo", []PullRequestState{PullRequestStateMerged},
+		IssueOrderFieldCreatedAt, OrderDirectionDesc, -1, since(45))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 3 || !prs[0].SortTime.Equal(prs[0].MergedAt) {
+		t.Errorf("PullRequests() = %d, sort time %s", len(prs), prs[0].SortTime)
+	}
+
+	prs, err = memo.PullRequests(ctx, "foo", allPullRequestStates,
+		IssueOrderFieldCreatedAt, OrderDirectionDesc, 2, since(45))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 2 || !prs[0].SortTime.Equal(prs[0].UpdatedAt) {
+		t.Errorf("PullRequests() = %d, sort time %s", len(prs), prs[0].SortTime)
+	}
+	if src.count != 2 {
+		t.Errorf("fetched %d times, want 2", src.count)
+	}
+
+	// The older pull requests are fetched again
+	prs, err = memo.PullRequests(ctx, "foo", []PullRequestState{PullRequestStateOpen},
+		IssueOrderFieldCreatedAt, OrderDirectionDesc, -1, since(85))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 4 {
+		t.Errorf("PullRequests() = %d, want 4", len(prs))
+	}
+	if src.count != 3 {
+		t.Errorf("fetched %d times, want 3", src.count)
+	}
+
+	prs, err = memo.PullRequests(ctx, "foo", []PullRequestState{PullRequestStateMerged},
+		IssueOrderFieldCreatedAt, OrderDirectionDesc, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 2 || src.count != 3 {
+		t.Errorf("PullRequests() = %d, fetched %d times", len(prs), src.count)
+	}
+
+	// The copies are served
+	prs[0].Username = "bar"
+	prs, _ = memo.PullRequests(ctx, "foo", []PullRequestState{PullRequestStateMerged},
+		IssueOrderFieldCreatedAt, OrderDirectionDesc, 2)
+	if prs[0].Username != "" {
+		t.Errorf("PullRequests() username = %q, want unchanged", prs[0].Username)
+	}
+
+	if _, ok := memo.(CommitLister); !ok {
+		t.Errorf("NewMemo() lost the CommitLister")
+	}
+}
+
+func TestMemoPullRequestsCallbacks(t *testing.T) {
+	now := time.Now()
+	src := &countSource{}
+	for i := 0; i != 10; i++ {
+		src.prs = append(src.prs, &PullRequest{
+			Number:    i,
+			State:     string(PullRequestStateMerged),
+			CreatedAt: now.AddDate(0, 0, -10*i),
+			UpdatedAt: now.AddDate(0, 0, -10*i+1),
+			MergedAt:  now.AddDate(0, 0, -10*i+2),
+		})
+	}
+
+	memo := NewMemo(src)
+	// The first listing fetches, the second one is served and extended, the last one is served
+	for _, days := range []int{45, 85, 85} {
+		calls := map[int]int{}
+		prs, err := memo.PullRequests(context.Background(), "foo", []PullRequestState{PullRequestStateMerged},
+			IssueOrderFieldCreatedAt, OrderDirectionDesc, -1, func(pr *PullRequest) bool {
+				calls[pr.Number]++
+				if !pr.SortTime.Equal(pr.MergedAt) {
+					t.Errorf("callback sort time = %s, want the merged time", pr.SortTime)
+				}
+				pr.Username = "changed"
+				return pr.CreatedAt.After(now.AddDate(0, 0, -days))
+			})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(prs) != days/10+1 {
+			t.Errorf("PullRequests() since %d days = %d", days, len(prs))
+		}
+		for number, n := range calls {
+			if n != 1 {
+				t.Errorf("callback of #%d called %d times, want once", number, n)
+			}
+		}
+	}
+	if src.prs[0].Username != "" {
+		t.Errorf("callback changed the memoized pull request")
+	}
+}
+
+// blockSource blocks the stat until it's released, and fails if the context is canceled
+type blockSource struct {
+	Git
+	entered chan struct{}
+	release chan struct{}
+	count   int
+}
+
+func (s *blockSource) Stat(ctx context.Context, username string, from, to time.Time) (*Stat, error) {
+	s.count++
+	close(s.entered)
+	<-s.release
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &Stat{Commits: 1}, nil
+}
+
+func TestMemoCanceled(t *testing.T) {
+	src := &blockSource{
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	memo := NewMemo(src)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		_, err := memo.Stat(ctx, "foo", time.Time{}, time.Time{})
+		errs <- err
+	}()
+	<-src.entered
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("Stat() canceled error = %v", err)
+	}
+
+	// The shared fetch goes on for the others
+	close(src.release)
+	stat, err := memo.Stat(context.Background(), "foo", time.Time{}, time.Time{})
+	if err != nil || stat.Commits != 1 || src.count != 1 {
+		t.Errorf("Stat() = %v, %v, fetched %d times", stat, err, src.count)
+	}
+}
+
+func TestMemoIterPullRequests(t *testing.T) {
+	src := &countSource{}
+	for i := 0; i != 5; i++ {
+		src.prs = append(src.prs, &PullRequest{Number: i, State: string(PullRequestStateOpen)})
+	}
+	memo := NewMemo(src)
+	for _, stop := range []int{2, 5} {
+		var numbers []int
+		for pr, err := range IterPullRequests(context.Background(), memo, "foo", nil, IssueOrderFieldCreatedAt, OrderDirectionDesc) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			numbers = append(numbers, pr.Number)
+			if len(numbers) == stop {
+				break
+			}
+		}
+		if len(numbers) != stop || numbers[len(numbers)-1] != stop-1 {
+			t.Errorf("IterPullRequests() = %v, want the first %d once", numbers, stop)
+		}
+	}
+}
+
+func TestMemoIterPullRequestsCaller(t *testing.T) {
+	src := &countSource{}
+	for i := 0; i != 5; i++ {
+		src.prs = append(src.prs, &PullRequest{Number: i, State: string(PullRequestStateOpen)})
+	}
+	memo := NewMemo(src)
+
+	// The panic of the loop body reaches the caller
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recover() = %v, want boom", r)
+			}
+		}()
+		for range IterPullRequests(context.Background(), memo, "foo", nil, IssueOrderFieldCreatedAt, OrderDirectionDesc) {
+			panic("boom")
+		}
+	}()
+
+	// The loop body doesn't block the other listings of the same key
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range IterPullRequests(context.Background(), memo, "bar", nil, IssueOrderFieldCreatedAt, OrderDirectionDesc) {
+			close(entered)
+			<-release
+			break
+		}
+	}()
+	<-entered
+	prs, err := memo.PullRequests(context.Background(), "bar", nil, IssueOrderFieldCreatedAt, OrderDirectionDesc, -1)
+	close(release)
+	<-done
+	if err != nil || len(prs) != 5 {
+		t.Errorf("PullRequests() = %d, %v", len(prs), err)
+	}
+}
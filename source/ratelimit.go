@@ -0,0 +1,220 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// rateLimitRetry is the maximum times to wait for the rate limit of a request
+	rateLimitRetry = 5
+	// secondaryRateLimitBackoff is the first wait of the secondary rate limit without Retry-After,
+	// GitHub recommends waiting at least one minute.
+	secondaryRateLimitBackoff = time.Minute
+)
+
+// rateLimitRequest pauses the requests when the quota of the resource is exhausted,
+// and retries the requests limited by the server.
+type rateLimitRequest struct {
+	roundTripper http.RoundTripper
+	retry        int
+	backoff      time.Duration
+	now          func() time.Time
+	sleep        func(ctx context.Context, d time.Duration) error
+
+	mut    sync.Mutex
+	resets map[string]time.Time
+}
+
+func newRateLimitRequest(roundTripper http.RoundTripper) *rateLimitRequest {
+	return &rateLimitRequest{
+		roundTripper: roundTripper,
+		retry:        rateLimitRetry,
+		backoff:      secondaryRateLimitBackoff,
+		now:          time.Now,
+		sleep:        sleepContext,
+		resets:       map[string]time.Time{},
+	}
+}
+
+func (l *rateLimitRequest) RoundTrip(r *http.Request) (*http.Response, error) {
+	resource := rateLimitResource(r)
+
+	var body []byte
+	if r.Body != nil && r.GetBody == nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	for i := 0; ; i++ {
+		err := l.waitReset(r.Context(), resource)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := l.roundTripper.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+		l.update(resource, resp.Header)
+
+		wait, limited := l.limited(resp, i)
+		if !limited || i >= l.retry {
+			return resp, nil
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		err = l.sleep(r.Context(), wait)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case r.GetBody != nil:
+			r.Body, err = r.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		case body != nil:
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+	}
+}
+
+// waitReset waits until the quota of the resource is reset if it's exhausted
+func (l *rateLimitRequest) waitReset(ctx context.Context, resource string) error {
+	l.mut.Lock()
+	reset, ok := l.resets[resource]
+	l.mut.Unlock()
+	if !ok {
+		return nil
+	}
+	if d := reset.Sub(l.now()); d > 0 {
+		err := l.sleep(ctx, d)
+		if err != nil {
+			return err
+		}
+	}
+
+	l.mut.Lock()
+	if l.resets[resource].Equal(reset) {
+		delete(l.resets, resource)
+	}
+	l.mut.Unlock()
+	return nil
+}
+
+// update records the reset time of the resource when its quota is exhausted
+func (l *rateLimitRequest) update(resource string, header http.Header) {
+	remaining, ok := headerInt(header, "X-RateLimit-Remaining", "RateLimit-Remaining")
+	if !ok || remaining > 0 {
+		return
+	}
+	reset, ok := headerInt(header, "X-RateLimit-Reset", "RateLimit-Reset")
+	if !ok {
+		return
+	}
+	l.mut.Lock()
+	// One more second as the clock of the server may be slightly ahead
+	l.resets[resource] = time.Unix(int64(reset)+1, 0)
+	l.mut.Unlock()
+}
+
+// limited reports whether the response is limited by the server, and how long to wait before retrying
+func (l *rateLimitRequest) limited(resp *http.Response, i int) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	if after, ok := headerInt(resp.Header, "Retry-After"); ok {
+		return time.Duration(after) * time.Second, true
+	}
+
+	if remaining, ok := headerInt(resp.Header, "X-RateLimit-Remaining", "RateLimit-Remaining"); ok && remaining == 0 {
+		// The wait is done before the next request
+		return 0, true
+	}
+
+	if resp.StatusCode == http.StatusForbidden && !isSecondaryRateLimit(resp) {
+		return 0, false
+	}
+
+	backoff := l.backoff << i
+	jitter := time.Duration(rand.Int63n(int64(l.backoff)))
+	return backoff + jitter, true
+}
+
+// isSecondaryRateLimit reports whether the forbidden response is of the secondary rate limit,
+// the body is kept for the caller.
+func isSecondaryRateLimit(resp *http.Response) bool {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	return bytes.Contains(bytes.ToLower(body), []byte("secondary rate limit"))
+}
+
+// rateLimitResource returns the resource of the quota which the request is counted in,
+// each token of the pool has its own quota.
+func rateLimitResource(r *http.Request) string {
+	resource := apiResource(r)
+	if i, ok := tokenIndex(r.Context()); ok {
+		return tokenResource(resource, i)
+	}
+	return resource
+}
+
+// apiResource returns the resource of the API which the request is counted in
+func apiResource(r *http.Request) string {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/graphql"):
+		return r.URL.Host + " graphql"
+	case strings.Contains(r.URL.Path, "/search/"):
+		return r.URL.Host + " search"
+	}
+	return r.URL.Host + " core"
+}
+
+func headerInt(header http.Header, keys ...string) (int, bool) {
+	for _, key := range keys {
+		v := header.Get(key)
+		if v == "" {
+			continue
+		}
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			continue
+		}
+		return i, true
+	}
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
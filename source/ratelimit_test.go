@@ -0,0 +1,153 @@
+package source
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRateLimitRequest returns the transport which records the sleeps instead of sleeping
+func newTestRateLimitRequest(now time.Time, sleeps *[]time.Duration) *rateLimitRequest {
+	l := newRateLimitRequest(http.DefaultTransport)
+	l.backoff = time.Second
+	l.now = func() time.Time {
+		return now
+	}
+	l.sleep = func(ctx context.Context, d time.Duration) error {
+		*sleeps = append(*sleeps, d)
+		return nil
+	}
+	return l
+}
+
+func TestRateLimitExhausted(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	reset := now.Add(time.Minute)
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		count++
+		rw.Header().Set("X-RateLimit-Remaining", strconv.Itoa(2-count))
+		rw.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	}))
+	defer server.Close()
+
+	var sleeps []time.Duration
+	cli := &http.Client{Transport: newTestRateLimitRequest(now, &sleeps)}
+	for i := 0; i != 3; i++ {
+		resp, err := cli.Get(server.URL + "/graphql")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if len(sleeps) != 1 || sleeps[0] != time.Minute+time.Second {
+		t.Errorf("sleeps = %v, want [%s]", sleeps, time.Minute+time.Second)
+	}
+
+	// The quota of the other resource isn't exhausted
+	sleeps = nil
+	resp, err := cli.Get(server.URL + "/users/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(sleeps) != 0 {
+		t.Errorf("sleeps = %v, want none", sleeps)
+	}
+}
+
+func TestRateLimitRetryAfter(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "query" {
+			t.Errorf("body = %q, want %q", body, "query")
+		}
+		count++
+		if count == 1 {
+			rw.Header().Set("Retry-After", "3")
+			rw.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		rw.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var sleeps []time.Duration
+	cli := &http.Client{Transport: newTestRateLimitRequest(time.Now(), &sleeps)}
+	resp, err := cli.Post(server.URL+"/graphql", "text/plain", strings.NewReader("query"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || count != 2 {
+		t.Errorf("status = %d, requests = %d", resp.StatusCode, count)
+	}
+	if len(sleeps) != 1 || sleeps[0] != 3*time.Second {
+		t.Errorf("sleeps = %v, want [3s]", sleeps)
+	}
+}
+
+func TestRateLimitSecondary(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		count++
+		if count <= 3 {
+			rw.WriteHeader(http.StatusForbidden)
+			rw.Write([]byte(`{"message":"You have exceeded a secondary rate limit."}`))
+			return
+		}
+		rw.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var sleeps []time.Duration
+	cli := &http.Client{Transport: newTestRateLimitRequest(time.Now(), &sleeps)}
+	resp, err := cli.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d", resp.StatusCode)
+	}
+	if len(sleeps) != 3 {
+		t.Fatalf("sleeps = %v, want 3 backoffs", sleeps)
+	}
+	for i, d := range sleeps {
+		min := time.Second << i
+		if d < min || d >= min+time.Second {
+			t.Errorf("backoff %d = %s, want in [%s, %s)", i, d, min, min+time.Second)
+		}
+	}
+}
+
+func TestRateLimitForbidden(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		count++
+		rw.WriteHeader(http.StatusForbidden)
+		rw.Write([]byte(`{"message":"Resource not accessible by integration"}`))
+	}))
+	defer server.Close()
+
+	var sleeps []time.Duration
+	cli := &http.Client{Transport: newTestRateLimitRequest(time.Now(), &sleeps)}
+	resp, err := cli.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusForbidden || count != 1 || len(sleeps) != 0 {
+		t.Errorf("status = %d, requests = %d, sleeps = %v", resp.StatusCode, count, sleeps)
+	}
+	if !strings.Contains(string(body), "Resource not accessible") {
+		t.Errorf("body = %q, want kept", body)
+	}
+}
@@ -0,0 +1,57 @@
+package source
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Factory creates the source of a provider, an empty baseURL selects the provider's default endpoint
+type Factory func(baseURL string) (Source, error)
+
+// Registry selects the source by provider name and base URL, each source is created once and reused,
+// and its queries are memoized in the run.
+type Registry struct {
+	mut       sync.Mutex
+	def       string
+	factories map[string]Factory
+	sources   map[string]Source
+}
+
+func NewRegistry(def string) *Registry {
+	return &Registry{
+		def:       def,
+		factories: map[string]Factory{},
+		sources:   map[string]Source{},
+	}
+}
+
+func (r *Registry) Register(provider string, factory Factory) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.factories[provider] = factory
+}
+
+// Get returns the source of the provider, an empty provider selects the default provider
+func (r *Registry) Get(provider, baseURL string) (Source, error) {
+	if provider == "" {
+		provider = r.def
+	}
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	key := provider + " " + baseURL
+	if src, ok := r.sources[key]; ok {
+		return src, nil
+	}
+	factory, ok := r.factories[provider]
+	if !ok {
+		return nil, fmt.Errorf("not support provider %q", provider)
+	}
+	src, err := factory(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("provider %q: %w", provider, err)
+	}
+	src = NewMemo(src)
+	r.sources[key] = src
+	return src, nil
+}
@@ -0,0 +1,114 @@
+package source
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/wzshiming/httpcache"
+	"golang.org/x/oauth2"
+)
+
+// restClient is a JSON client for the REST API of the other forges
+type restClient struct {
+	baseURL string
+	cli     *http.Client
+}
+
+func newRESTClient(baseURL string, token string, cache CachePolicy, interval time.Duration, retry int) *restClient {
+	transport := http.DefaultTransport
+	if token != "" {
+		src := oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: token},
+		)
+		transport = oauth2.NewClient(context.Background(), src).Transport
+	}
+
+	transport = newIntervalRequest(newRateLimitRequest(cache.transport(transport)), interval, retry)
+	return &restClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		cli: &http.Client{
+			Transport: httpcache.NewRoundTripper(transport,
+				append([]httpcache.Option{
+					httpcache.WithFilterer(
+						httpcache.MethodFilterer(http.MethodGet),
+					),
+				}, cache.options(
+					httpcache.MethodKeyer(),
+					httpcache.HostKeyer(),
+					httpcache.PathKeyer(),
+					rawQueryKeyer(),
+				)...)...,
+			),
+		},
+	}
+}
+
+// get decodes the JSON response of path into out
+func (c *restClient) get(ctx context.Context, path string, query url.Values, out interface{}) (http.Header, error) {
+	u := c.baseURL + path
+	if len(query) != 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("GET %s: %s: %s", path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	err = json.NewDecoder(resp.Body).Decode(out)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", path, err)
+	}
+	return resp.Header, nil
+}
+
+// asset fetches the content of the URI and its type, the URI is not relative to the base URL
+func (c *restClient) asset(ctx context.Context, uri string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := c.cli.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("GET %s: %s", uri, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("GET %s: %w", uri, err)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	return body, contentType, nil
+}
+
+func rawQueryKeyer() httpcache.Keyer {
+	return httpcache.KeyerFunc(func(req *http.Request) string {
+		if req.URL.RawQuery == "" {
+			return ""
+		}
+		hash := md5.Sum([]byte(req.URL.RawQuery))
+		return hex.EncodeToString(hash[:])
+	})
+}
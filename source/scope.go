@@ -0,0 +1,50 @@
+package source
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The kinds of the scopes
+const (
+	ScopeRepository = "repo"
+	ScopeOrg        = "org"
+	// ScopeSearch is the pull requests found by a search query, it's not parsed by ParseScope
+	ScopeSearch = "search"
+)
+
+// Scope is a repository, an organization or a search whose pull requests are listed regardless of the authors
+type Scope struct {
+	Kind string
+	// Name is owner/name of the repository, the name of the organization, or the search query
+	Name string
+}
+
+// ParseScope parses the scope like "repo:owner/name" or "org:name"
+func ParseScope(s string) (Scope, error) {
+	kind, name, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok || name == "" {
+		return Scope{}, fmt.Errorf("scope %q: not match repo:owner/name or org:name", s)
+	}
+	switch kind {
+	case ScopeRepository:
+		owner, repo, ok := strings.Cut(name, "/")
+		if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+			return Scope{}, fmt.Errorf("scope %q: not match repo:owner/name", s)
+		}
+	case ScopeOrg:
+		if strings.Contains(name, "/") {
+			return Scope{}, fmt.Errorf("scope %q: not match org:name", s)
+		}
+	default:
+		return Scope{}, fmt.Errorf("scope %q: not support kind %q", s, kind)
+	}
+	return Scope{
+		Kind: kind,
+		Name: name,
+	}, nil
+}
+
+func (s Scope) String() string {
+	return s.Kind + ":" + s.Name
+}
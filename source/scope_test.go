@@ -0,0 +1,23 @@
+package source
+
+import (
+	"testing"
+)
+
+func TestParseScope(t *testing.T) {
+	for s, want := range map[string]Scope{
+		"repo:foo/bar": {Kind: ScopeRepository, Name: "foo/bar"},
+		"org:foo":      {Kind: ScopeOrg, Name: "foo"},
+	} {
+		got, err := ParseScope(s)
+		if err != nil || got != want {
+			t.Errorf("ParseScope(%q) = %v, %v", s, got, err)
+		}
+	}
+	for _, s := range []string{"foo", "repo:foo", "repo:foo/bar/baz", "org:foo/bar", "user:foo"} {
+		_, err := ParseScope(s)
+		if err == nil {
+			t.Errorf("ParseScope(%q) want error", s)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/url"
+	"time"
+)
+
+const MaxPageSize = 100
+
+var ErrNotSupported = fmt.Errorf("not supported")
+
+// Source is the data source used by the generators
+type Source interface {
+	// Stat counts the contributions of the user between from and to, zero times select the last year
+	Stat(ctx context.Context, username string, from, to time.Time) (*Stat, error)
+	// LifetimeStat counts the contributions of the user across all the years
+	LifetimeStat(ctx context.Context, username string) (*Stat, error)
+	// Repositories lists all the repositories owned by the user
+	Repositories(ctx context.Context, username string) ([]*Repository, error)
+	// OrgStat counts the contributions of the user to the organization between from and to, zero times select the last year
+	OrgStat(ctx context.Context, username string, org string, from, to time.Time) (*OrgStat, error)
+	PullRequests(ctx context.Context, username string, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...PullRequestCallback) ([]*PullRequest, error)
+	// ScopedPullRequests lists the pull requests of all the authors in the repository or the organization of the scope
+	ScopedPullRequests(ctx context.Context, scope Scope, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...PullRequestCallback) ([]*PullRequest, error)
+	Issues(ctx context.Context, username string, states []IssueState, orderField IssueOrderField, orderDirection OrderDirection, size int, cbs ...IssueCallback) ([]*Issue, error)
+	// Reviews lists the pull request reviews submitted by the user between from and to, newest first
+	Reviews(ctx context.Context, username string, from, to time.Time, size int, cbs ...ReviewCallback) ([]*Review, error)
+}
+
+var (
+	_ Source              = (*GitHub)(nil)
+	_ PullRequestIterator = (*GitHub)(nil)
+	_ AssetFetcher        = (*GitHub)(nil)
+)
+
+type Stat struct {
+	Name          string
+	Stars         int
+	Forks         int
+	Issues        int
+	Commits       int
+	Reviews       int
+	PullRequests  int
+	ContributedTo int
+}
+
+type Repository struct {
+	Name       string
+	Stars      int
+	Forks      int
+	IsFork     bool
+	IsArchived bool
+}
+
+type OrgStat struct {
+	Name         string
+	LogoURL      string
+	Issues       int
+	Commits      int
+	Reviews      int
+	PullRequests int
+}
+
+type PullRequest struct {
+	Username   string
+	Repository string
+	Number     int
+	Title      string
+	URL        *url.URL
+	BaseRef    string
+	State      string
+	Additions  int
+	Deletions  int
+	// Commits is the number of the commits landed on the base branch if it's merged, otherwise of the pull request
+	Commits      int
+	ChangedFiles int
+	ChangeSize   string
+	CreatedAt    time.Time
+	ClosedAt     time.Time
+	MergedAt     time.Time
+	UpdatedAt    time.Time
+	Labels       []string
+	SortTime     time.Time
+	// MergeStrategy is one of PullRequestMergeMethod, it's empty if it's not merged or unknown
+	MergeStrategy string
+	IsDraft       bool
+	// ReviewDecision is one of PullRequestReviewDecision, it's empty if the review is not required
+	ReviewDecision string
+	// RequestedReviewers are the users and the teams (org/team) requested to review
+	RequestedReviewers []string
+	// Approvers are the users whose latest review approves
+	Approvers []string
+	// Comments is the number of the comments, not including the review comments
+	Comments int
+	MergedBy string
+	// FirstReviewAt is when the first review of the others than the author is submitted
+	FirstReviewAt time.Time
+}
+
+// TimeToFirstReview returns the time from the creation to the first review, it's zero if it's not reviewed
+func (p *PullRequest) TimeToFirstReview() time.Duration {
+	if p.FirstReviewAt.IsZero() {
+		return 0
+	}
+	return p.FirstReviewAt.Sub(p.CreatedAt)
+}
+
+// PullRequestCallback is called for each pull request in order, returning false stops the listing
+type PullRequestCallback func(pr *PullRequest) bool
+
+// PullRequestIterator is implemented by the sources which fetch the pages of the pull requests lazily
+type PullRequestIterator interface {
+	// IterPullRequests iterates the pull requests created by the user, the next page is fetched when the previous one is consumed
+	IterPullRequests(ctx context.Context, username string, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection) iter.Seq2[*PullRequest, error]
+}
+
+// AssetFetcher is implemented by the sources which fetch the assets like the avatars through their cache
+type AssetFetcher interface {
+	// Asset fetches the content of the URI and its type
+	Asset(ctx context.Context, uri string) ([]byte, string, error)
+}
+
+// IterPullRequests iterates the pull requests created by the user, the pages are fetched lazily if the source is a PullRequestIterator,
+// otherwise the listing of the source stops when the iteration stops. The iteration ends after an error.
+func IterPullRequests(ctx context.Context, src Source, username string, states []PullRequestState, orderField IssueOrderField, orderDirection OrderDirection) iter.Seq2[*PullRequest, error] {
+	if it, ok := src.(PullRequestIterator); ok {
+		return it.IterPullRequests(ctx, username, states, orderField, orderDirection)
+	}
+	return iterPullRequests(func(cb PullRequestCallback) error {
+		_, err := src.PullRequests(ctx, username, states, orderField, orderDirection, -1, cb)
+		return err
+	})
+}
+
+// iterPullRequests iterates the pull requests passed to the callback of the listing, the listing is stopped
+// when the iteration stops, and yield is never called again even if the listing calls the callback.
+func iterPullRequests(list func(cb PullRequestCallback) error) iter.Seq2[*PullRequest, error] {
+	return func(yield func(*PullRequest, error) bool) {
+		stopped := false
+		err := list(func(pr *PullRequest) bool {
+			if stopped {
+				return false
+			}
+			stopped = !yield(pr, nil)
+			return !stopped
+		})
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
+
+type Issue struct {
+	Username   string
+	Repository string
+	Number     int
+	Title      string
+	URL        *url.URL
+	State      string
+	Comments   int
+	CreatedAt  time.Time
+	ClosedAt   time.Time
+	UpdatedAt  time.Time
+	Labels     []string
+	SortTime   time.Time
+}
+
+// IssueCallback is called for each issue in order, returning false stops the listing
+type IssueCallback func(issue *Issue) bool
+
+type Review struct {
+	Username    string
+	Repository  string
+	Number      int
+	Title       string
+	URL         *url.URL
+	BaseRef     string
+	Author      string
+	State       string
+	SubmittedAt time.Time
+}
+
+// ReviewCallback is called for each review in order, returning false stops the listing
+type ReviewCallback func(review *Review) bool
+
+// timeWindow fills the zero times, the window ends now and spans one year by default
+func timeWindow(from, to time.Time) (time.Time, time.Time) {
+	if to.IsZero() {
+		to = time.Now().UTC()
+	}
+	if from.IsZero() {
+		from = to.AddDate(-1, 0, 0)
+	}
+	return from, to
+}
+
+// setSortTime sorts by the time matching the state when only one state is listed
+func setSortTime(p *PullRequest, states []PullRequestState) {
+	if len(states) != 1 {
+		return
+	}
+	switch states[0] {
+	case PullRequestStateMerged:
+		p.SortTime = p.MergedAt
+	case PullRequestStateClosed:
+		p.SortTime = p.ClosedAt
+	case PullRequestStateOpen:
+		p.SortTime = p.CreatedAt
+	}
+}
+
+// setIssueSortTime sorts by the time matching the state when only one state is listed
+func setIssueSortTime(i *Issue, states []IssueState) {
+	if len(states) != 1 {
+		return
+	}
+	switch states[0] {
+	case IssueStateClosed:
+		i.SortTime = i.ClosedAt
+	case IssueStateOpen:
+		i.SortTime = i.CreatedAt
+	}
+}
+
+func changeSize(i int) string {
+	switch {
+	case i < 10:
+		return "XS"
+	case i < 30:
+		return "S"
+	case i < 100:
+		return "M"
+	case i < 500:
+		return "L"
+	case i < 1000:
+		return "XL"
+	default:
+		return "XXL"
+	}
+}
@@ -0,0 +1,216 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wzshiming/httpcache"
+)
+
+// errTokenExhausted is returned by the token whose quota is exhausted, the request is tried with another token
+var errTokenExhausted = errors.New("token quota exhausted")
+
+// quota is the budget of a token in a resource
+type quota struct {
+	remaining int
+	reset     time.Time
+}
+
+func (q quota) exhausted(now time.Time) bool {
+	return q.remaining <= 0 && now.Before(q.reset)
+}
+
+// tokenPool rotates the tokens, a token is used until its quota of the resource is exhausted.
+// The request is pinned to a token before the cache, so the responses are cached for each token,
+// and authenticated with the pinned token under the cache.
+type tokenPool struct {
+	tokens []string
+	ids    []string
+	now    func() time.Time
+
+	mut     sync.Mutex
+	current int
+	quotas  map[string]quota
+}
+
+func newTokenPool(tokens []string) *tokenPool {
+	ids := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		hash := sha256.Sum256([]byte(token))
+		ids = append(ids, "token-"+hex.EncodeToString(hash[:])[:12])
+	}
+	return &tokenPool{
+		tokens: tokens,
+		ids:    ids,
+		now:    time.Now,
+		quotas: map[string]quota{},
+	}
+}
+
+type tokenKey struct{}
+
+func withToken(ctx context.Context, i int) context.Context {
+	return context.WithValue(ctx, tokenKey{}, i)
+}
+
+// tokenIndex returns the token which the request is pinned to
+func tokenIndex(ctx context.Context) (int, bool) {
+	i, ok := ctx.Value(tokenKey{}).(int)
+	return i, ok
+}
+
+// tokenResource returns the resource of the quota of the token
+func tokenResource(resource string, i int) string {
+	return resource + " token" + strconv.Itoa(i)
+}
+
+// pick returns the current token if it has quota of the resource, or rotates to the next one.
+// If all the tokens are exhausted, it returns the one reset first.
+func (p *tokenPool) pick(resource string) int {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	now := p.now()
+	first := -1
+	var reset time.Time
+	for j := range p.tokens {
+		i := (p.current + j) % len(p.tokens)
+		q, ok := p.quotas[tokenResource(resource, i)]
+		if !ok || !q.exhausted(now) {
+			p.current = i
+			return i
+		}
+		if first == -1 || q.reset.Before(reset) {
+			first = i
+			reset = q.reset
+		}
+	}
+	return first
+}
+
+// update records the quota of the token from the response, and reports whether it's exhausted
+func (p *tokenPool) update(resource string, header http.Header) bool {
+	remaining, ok := headerInt(header, "X-RateLimit-Remaining", "RateLimit-Remaining")
+	if !ok {
+		return false
+	}
+	reset, _ := headerInt(header, "X-RateLimit-Reset", "RateLimit-Reset")
+	p.mut.Lock()
+	p.quotas[resource] = quota{
+		remaining: remaining,
+		reset:     time.Unix(int64(reset)+1, 0),
+	}
+	p.mut.Unlock()
+	return remaining <= 0
+}
+
+// available reports whether another token than i has quota of the resource
+func (p *tokenPool) available(resource string, i int) bool {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	now := p.now()
+	for j := range p.tokens {
+		if j == i {
+			continue
+		}
+		q, ok := p.quotas[tokenResource(resource, j)]
+		if !ok || !q.exhausted(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyer partitions the cache by the pinned token, the tokens are identified by their hashes
+func (p *tokenPool) keyer() httpcache.Keyer {
+	return httpcache.KeyerFunc(func(r *http.Request) string {
+		i, _ := tokenIndex(r.Context())
+		return p.ids[i]
+	})
+}
+
+// pin pins the requests to the tokens, and retries with another token if the pinned one is exhausted
+func (p *tokenPool) pin(roundTripper http.RoundTripper) http.RoundTripper {
+	return &tokenPinRequest{
+		pool:         p,
+		roundTripper: roundTripper,
+	}
+}
+
+// auth authenticates the requests with the pinned tokens
+func (p *tokenPool) auth(roundTripper http.RoundTripper) http.RoundTripper {
+	return &tokenAuthRequest{
+		pool:         p,
+		roundTripper: roundTripper,
+	}
+}
+
+type tokenPinRequest struct {
+	pool         *tokenPool
+	roundTripper http.RoundTripper
+}
+
+func (t *tokenPinRequest) RoundTrip(r *http.Request) (*http.Response, error) {
+	resource := apiResource(r)
+
+	var body []byte
+	if r.Body != nil && r.GetBody == nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	for i := 0; ; i++ {
+		token := t.pool.pick(resource)
+		resp, err := t.roundTripper.RoundTrip(r.WithContext(withToken(r.Context(), token)))
+		if !errors.Is(err, errTokenExhausted) || i >= len(t.pool.tokens) {
+			return resp, err
+		}
+
+		switch {
+		case r.GetBody != nil:
+			r.Body, err = r.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		case body != nil:
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+	}
+}
+
+type tokenAuthRequest struct {
+	pool         *tokenPool
+	roundTripper http.RoundTripper
+}
+
+func (t *tokenAuthRequest) RoundTrip(r *http.Request) (*http.Response, error) {
+	i, _ := tokenIndex(r.Context())
+	req := r.Clone(r.Context())
+	req.Header.Set("Authorization", "Bearer "+t.pool.tokens[i])
+	resp, err := t.roundTripper.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	exhausted := t.pool.update(rateLimitResource(r), resp.Header)
+	if exhausted &&
+		(resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) &&
+		t.pool.available(apiResource(r), i) {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, errTokenExhausted
+	}
+	return resp, nil
+}
@@ -0,0 +1,88 @@
+package source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenPool(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		tokens = append(tokens, token)
+		rw.Header().Set("X-RateLimit-Reset", reset)
+		switch token {
+		case "a":
+			rw.Header().Set("X-RateLimit-Remaining", "0")
+		case "b":
+			rw.Header().Set("X-RateLimit-Remaining", "100")
+		case "c":
+			// The quota is exhausted by others
+			rw.Header().Set("X-RateLimit-Remaining", "0")
+			rw.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	newClient := func(pool *tokenPool, sleeps *[]time.Duration) *http.Client {
+		l := newTestRateLimitRequest(time.Now(), sleeps)
+		l.roundTripper = pool.auth(http.DefaultTransport)
+		return &http.Client{Transport: pool.pin(l)}
+	}
+
+	var sleeps []time.Duration
+	cli := newClient(newTokenPool([]string{"a", "b"}), &sleeps)
+	for i := 0; i != 3; i++ {
+		resp, err := cli.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if strings.Join(tokens, ",") != "a,b,b" || len(sleeps) != 0 {
+		t.Errorf("tokens = %v, sleeps = %v, want rotated without waiting", tokens, sleeps)
+	}
+
+	tokens = nil
+	cli = newClient(newTokenPool([]string{"c", "b"}), &sleeps)
+	resp, err := cli.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || strings.Join(tokens, ",") != "c,b" || len(sleeps) != 0 {
+		t.Errorf("status = %d, tokens = %v, sleeps = %v, want retried with the next token", resp.StatusCode, tokens, sleeps)
+	}
+
+	// All the tokens are exhausted, the limited response is waited
+	tokens = nil
+	cli = newClient(newTokenPool([]string{"c", "c"}), &sleeps)
+	resp, err = cli.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(sleeps) == 0 {
+		t.Errorf("tokens = %v, want waited for the reset", tokens)
+	}
+}
+
+func TestTokenPoolKeyer(t *testing.T) {
+	pool := newTokenPool([]string{"secret-a", "secret-b"})
+	keyer := pool.keyer()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	a := keyer.Key(r.WithContext(withToken(context.Background(), 0)))
+	b := keyer.Key(r.WithContext(withToken(context.Background(), 1)))
+	if a == b {
+		t.Errorf("keys of the tokens = %q, want partitioned", a)
+	}
+	if strings.Contains(a+b, "secret") {
+		t.Errorf("keys = %q %q, want the tokens hashed", a, b)
+	}
+}
@@ -11,6 +11,9 @@ const (
 	negate = "^"
 )
 
+// DefaultConcurrency is the default number of the fetches in parallel
+const DefaultConcurrency = 4
+
 func Match(pattern []string, value string) bool {
 	matches := []string{}
 	exceptions := []string{}
@@ -87,6 +90,15 @@ func ParseTimeSpan(span string, now time.Time) (time.Time, error) {
 	return last, nil
 }
 
+// ParseExactTimeSpan returns the time of the span before now, unlike ParseTimeSpan it's not aligned to the first day of the month
+func ParseExactTimeSpan(span string, now time.Time) (time.Time, error) {
+	y, m, d, err := parseTimeSpan(span)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return now.AddDate(-y, -m, -d), nil
+}
+
 func parseTimeSpan(span string) (y, m, d int, err error) {
 	v := 0
 	u := ""
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"testing"
+	"time"
 )
 
 func TestMatch(t *testing.T) {
@@ -71,3 +72,86 @@ func TestMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestParseExactTimeSpan(t *testing.T) {
+	now := time.Date(2024, 5, 17, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		span    string
+		now     time.Time
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name: "days",
+			span: "30days",
+			now:  now,
+			want: time.Date(2024, 4, 17, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "months",
+			span: "3month",
+			now:  now,
+			want: time.Date(2024, 2, 17, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "year",
+			span: "1year",
+			now:  now,
+			want: time.Date(2023, 5, 17, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "months of a year",
+			span: "12months",
+			now:  now,
+			want: time.Date(2023, 5, 17, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "days of a leap year",
+			span: "365days",
+			now:  time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2023, 3, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "year from the leap day",
+			span: "1year",
+			now:  time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "empty",
+			span:    "",
+			now:     now,
+			wantErr: true,
+		},
+		{
+			name:    "no number",
+			span:    "year",
+			now:     now,
+			wantErr: true,
+		},
+		{
+			name:    "no unit",
+			span:    "1",
+			now:     now,
+			wantErr: true,
+		},
+		{
+			name:    "unknown unit",
+			span:    "2weeks",
+			now:     now,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExactTimeSpan(tt.span, tt.now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseExactTimeSpan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseExactTimeSpan() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}